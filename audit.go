@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// Severities from the most to the least important, the order of the reports
+var severities = []string{SeverityHigh, SeverityMedium, SeverityLow}
+
+type AuditIssue struct {
+	Severity string `json:"severity"`
+	// short identifier of the check that failed, e.g. "duplicate-title"
+	Check   string `json:"check"`
+	Address string `json:"address"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+type AuditOptions struct {
+	// addresses longer than this are reported
+	MaxUrlLength int
+	// pages with less words than this are reported as thin
+	MinWordCount int
+}
+
+func DefaultAuditOptions() AuditOptions {
+	return AuditOptions{MaxUrlLength: 115, MinWordCount: 200}
+}
+
+type AuditReport struct {
+	Root   string       `json:"root"`
+	Pages  int          `json:"pages"`
+	Issues []AuditIssue `json:"issues"`
+}
+
+func isNoIndex(info PageInfo) bool {
+	return strings.Contains(strings.ToLower(info.Robots), "noindex")
+}
+
+// Checks the metadata of the pages that were fetched successfully, pages
+// answering with an error have bigger problems and are left out, as are the
+// ones requested for their status only (both are still the target of
+// canonical and hreflang checks)
+func AuditCrawl(root url.URL, store PageStore, options AuditOptions) (AuditReport, error) {
+	pages := make([]HtmlPageLinks, 0, store.Len())
+	crawled := make(map[url.URL]HtmlPageLinks, store.Len())
+	err := store.Each(func(page HtmlPageLinks) error {
+		crawled[page.Address] = page
+		if !isBrokenStatus(page.Response.StatusCode) && !page.Response.StatusOnly {
+			pages = append(pages, page)
+		}
+		return nil
+	})
+	if err != nil {
+		return AuditReport{}, err
+	}
+
+	report := AuditReport{Root: root.String(), Pages: len(pages), Issues: make([]AuditIssue, 0)}
+	issue := func(severity string, check string, address url.URL, detail string) {
+		report.Issues = append(report.Issues, AuditIssue{severity, check, address.String(), detail})
+	}
+
+	titles := make(map[string][]url.URL)
+	descriptions := make(map[string][]url.URL)
+	linkedFrom := make(map[url.URL]int)
+
+	for _, page := range pages {
+		info := page.Info
+
+		if info.Title == "" {
+			issue(SeverityHigh, "missing-title", page.Address, "")
+		} else {
+			titles[info.Title] = append(titles[info.Title], page.Address)
+		}
+
+		if info.Description == "" {
+			issue(SeverityMedium, "missing-description", page.Address, "")
+		} else {
+			descriptions[info.Description] = append(descriptions[info.Description], page.Address)
+		}
+
+		if len(info.H1s) > 1 {
+			issue(SeverityMedium, "multiple-h1", page.Address, fmt.Sprintf("%d h1 elements", len(info.H1s)))
+		}
+
+		if length := len(page.Address.String()); length > options.MaxUrlLength {
+			issue(SeverityLow, "long-url", page.Address, fmt.Sprintf("%d characters", length))
+		}
+
+		if info.Canonical != "" && info.Canonical != page.Address.String() {
+			issue(SeverityMedium, "canonical-elsewhere", page.Address, "canonical is "+info.Canonical)
+		}
+
+		if info.Soft404 != "" {
+			issue(SeverityHigh, "soft-404", page.Address, info.Soft404)
+		}
+
+		if info.WordCount < options.MinWordCount {
+			issue(SeverityLow, "thin-page", page.Address, fmt.Sprintf("%d words", info.WordCount))
+		}
+
+		for _, link := range page.LinksTo {
+			if link != page.Address {
+				linkedFrom[link]++
+			}
+		}
+	}
+
+	for _, page := range pages {
+		if isNoIndex(page.Info) && linkedFrom[page.Address] > 0 {
+			issue(SeverityHigh, "linked-noindex", page.Address,
+				fmt.Sprintf("robots %q, linked from %d pages", page.Info.Robots, linkedFrom[page.Address]))
+		}
+	}
+
+	duplicates := func(severity string, check string, groups map[string][]url.URL) {
+		for value, addresses := range groups {
+			if len(addresses) < 2 {
+				continue
+			}
+			for _, address := range addresses {
+				issue(severity, check, address, fmt.Sprintf("%q shared by %d pages", value, len(addresses)))
+			}
+		}
+	}
+	duplicates(SeverityHigh, "duplicate-title", titles)
+	duplicates(SeverityMedium, "duplicate-description", descriptions)
+
+	report.Issues = append(report.Issues, relationIssues(crawled)...)
+
+	sort.Slice(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Severity != b.Severity {
+			return severityRank(a.Severity) < severityRank(b.Severity)
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.Address < b.Address
+	})
+
+	return report, nil
+}
+
+func severityRank(severity string) int {
+	for rank, known := range severities {
+		if known == severity {
+			return rank
+		}
+	}
+	return len(severities)
+}
+
+// Issues of the given severity, in report order
+func (report AuditReport) BySeverity(severity string) []AuditIssue {
+	issues := make([]AuditIssue, 0)
+	for _, issue := range report.Issues {
+		if issue.Severity == severity {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func (report AuditReport) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (report AuditReport) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Audit of %s, %d pages, %d issues\n", report.Root, report.Pages, len(report.Issues))
+	for _, severity := range severities {
+		issues := report.BySeverity(severity)
+		fmt.Fprintf(w, "%s severity (%d)\n", strings.ToUpper(severity[:1])+severity[1:], len(issues))
+		for _, issue := range issues {
+			if issue.Detail != "" {
+				fmt.Fprintf(w, "  [%s] %s (%s)\n", issue.Check, issue.Address, issue.Detail)
+			} else {
+				fmt.Fprintf(w, "  [%s] %s\n", issue.Check, issue.Address)
+			}
+		}
+	}
+}
+
+var auditTemplate = template.Must(template.New("audit").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Audit of {{.Root}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.3em 0.6em; border-bottom: 1px solid #ddd; }
+h2.high { color: #b00020; } h2.medium { color: #b36b00; } h2.low { color: #555; }
+</style>
+</head>
+<body>
+<h1>Audit of {{.Root}}</h1>
+<p>{{.Pages}} pages, {{len .Report.Issues}} issues</p>
+{{range .Groups}}
+<h2 class="{{.Severity}}">{{.Severity}} severity ({{len .Issues}})</h2>
+{{if .Issues}}<table>
+<tr><th>Check</th><th>Page</th><th>Detail</th></tr>
+{{range .Issues}}<tr><td>{{.Check}}</td><td><a href="{{.Address}}">{{.Address}}</a></td><td>{{.Detail}}</td></tr>
+{{end}}</table>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+func (report AuditReport) WriteHtml(w io.Writer) error {
+	type group struct {
+		Severity string
+		Issues   []AuditIssue
+	}
+	groups := make([]group, 0, len(severities))
+	for _, severity := range severities {
+		groups = append(groups, group{severity, report.BySeverity(severity)})
+	}
+	return auditTemplate.Execute(w, struct {
+		Root   string
+		Pages  int
+		Report AuditReport
+		Groups []group
+	}{report.Root, report.Pages, report, groups})
+}
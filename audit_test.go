@@ -0,0 +1,91 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"net/url"
+)
+
+var _ = Describe("AuditCrawl", func() {
+
+	var (
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+		otherPageUrl *url.URL
+		hiddenPageUrl *url.URL
+		brokenPageUrl *url.URL
+		store MemoryPageStore
+		options AuditOptions
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+		otherPageUrl, _ = url.Parse("https://www.google.com/other-page-with-a-rather-long-address")
+		hiddenPageUrl, _ = url.Parse("https://www.google.com/hidden")
+		brokenPageUrl, _ = url.Parse("https://www.google.com/broken")
+
+		options = AuditOptions{MaxUrlLength: 50, MinWordCount: 10}
+		ok := ResponseInfo{StatusCode: 200}
+
+		store = make(MemoryPageStore)
+		store.Put(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl, *otherPageUrl, *hiddenPageUrl},
+			Response: ok,
+			Info: PageInfo{Title: "Google", Description: "Search", H1s: []string{"Google"}, WordCount: 100},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *aboutPageUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "Google", Description: "About", H1s: []string{"About", "Us"}, WordCount: 100},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *otherPageUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "Other", Canonical: pageUrl.String(), WordCount: 3},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *hiddenPageUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "Hidden", Description: "Hidden", Robots: "NOINDEX", WordCount: 100},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *brokenPageUrl,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 500},
+		})
+	})
+
+	It("should report the issues grouped by severity", func() {
+		report, err := AuditCrawl(*pageUrl, store, options)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Pages).To(Equal(4))
+		Expect(report.Issues).To(Equal([]AuditIssue{
+			{SeverityHigh, "duplicate-title", pageUrl.String(), `"Google" shared by 2 pages`},
+			{SeverityHigh, "duplicate-title", aboutPageUrl.String(), `"Google" shared by 2 pages`},
+			{SeverityHigh, "linked-noindex", hiddenPageUrl.String(), `robots "NOINDEX", linked from 1 pages`},
+			{SeverityMedium, "canonical-elsewhere", otherPageUrl.String(), "canonical is " + pageUrl.String()},
+			{SeverityMedium, "missing-description", otherPageUrl.String(), ""},
+			{SeverityMedium, "multiple-h1", aboutPageUrl.String(), "2 h1 elements"},
+			{SeverityLow, "long-url", otherPageUrl.String(), "60 characters"},
+			{SeverityLow, "thin-page", otherPageUrl.String(), "3 words"},
+		}))
+	})
+
+	It("should write the report as html", func() {
+		report, _ := AuditCrawl(*pageUrl, store, options)
+
+		var out bytes.Buffer
+		Expect(report.WriteHtml(&out)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring(`<h2 class="high">high severity (3)</h2>`))
+		Expect(out.String()).To(ContainSubstring(`&#34;Google&#34; shared by 2 pages`))
+	})
+
+})
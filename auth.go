@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Credentials sent to a single host, either basic (Username and Password) or
+// bearer (Token)
+type HostCredentials struct {
+	// host name, with the port only if the credentials are limited to it
+	Host     string
+	Username string
+	Password string
+	Token    string
+}
+
+func (credentials HostCredentials) matches(address *url.URL) bool {
+	if strings.Contains(credentials.Host, ":") {
+		return strings.EqualFold(credentials.Host, address.Host)
+	}
+	return strings.EqualFold(credentials.Host, address.Hostname())
+}
+
+// Parses host=user:password (basic) credentials as given on the command line
+func ParseBasicCredentials(definition string) (HostCredentials, error) {
+	parts := strings.SplitN(definition, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || !strings.Contains(parts[1], ":") {
+		return HostCredentials{}, fmt.Errorf("credentials %q are not in the host=user:password form", definition)
+	}
+	userPassword := strings.SplitN(parts[1], ":", 2)
+	return HostCredentials{Host: parts[0], Username: userPassword[0], Password: userPassword[1]}, nil
+}
+
+// Parses host=token (bearer) credentials as given on the command line
+func ParseBearerCredentials(definition string) (HostCredentials, error) {
+	parts := strings.SplitN(definition, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return HostCredentials{}, fmt.Errorf("credentials %q are not in the host=token form", definition)
+	}
+	return HostCredentials{Host: parts[0], Token: parts[1]}, nil
+}
+
+// Adds the credentials of the host to each request, redirects included. Being
+// decided request by request, credentials can't follow a redirect to another
+// host and an Authorization header meant for one host is never sent elsewhere
+type authTransport struct {
+	base        http.RoundTripper
+	credentials []HostCredentials
+}
+
+func (transport *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, credentials := range transport.credentials {
+		if !credentials.matches(req.URL) {
+			continue
+		}
+		// a RoundTripper must not modify the request it was given
+		req = req.Clone(req.Context())
+		if credentials.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+credentials.Token)
+		} else {
+			req.SetBasicAuth(credentials.Username, credentials.Password)
+		}
+		break
+	}
+	return transport.base.RoundTrip(req)
+}
+
+// Posts the fields (e.g. username and password) to the login form at the given
+// address, the session cookies it answers with are sent with the following
+// requests. The client must keep cookies (see HttpClientOptions.Cookies)
+func (client *DefaultHttpClient) Login(address url.URL, fields url.Values) error {
+	if client.Client == nil || client.Client.Jar == nil {
+		return fmt.Errorf("login needs a client keeping cookies")
+	}
+
+	req, err := client.newRequest("POST", address, strings.NewReader(fields.Encode()),
+		http.Header{"Content-Type": {"application/x-www-form-urlencoded"}})
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("login at %s answered %d", address.String(), resp.StatusCode)
+	}
+	if len(client.Client.Jar.Cookies(&address)) == 0 {
+		log.Warn("Login at ", address.String(), " did not set any cookie")
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+var _ = Describe("Authenticated DefaultHttpClient", func() {
+
+	var (
+		server *httptest.Server
+		requests chan *http.Request
+		serverUrl *url.URL
+		// same server, other host name
+		otherHostUrl *url.URL
+	)
+
+	BeforeEach(func() {
+		requests = make(chan *http.Request, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/redirect":
+				http.Redirect(w, r, otherHostUrl.String(), http.StatusFound)
+				return
+			case "/login":
+				r.ParseForm()
+				if r.PostForm.Get("password") != "secret" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "42", Path: "/"})
+			}
+			requests <- r
+		}))
+		serverUrl, _ = url.Parse(server.URL)
+		otherHostUrl, _ = url.Parse("http://localhost:" + serverUrl.Port() + "/")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	get := func(client *DefaultHttpClient, address url.URL) *http.Request {
+		resp, err := client.Get(address)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		return <-requests
+	}
+
+	It("should send the credentials to their host only", func() {
+		client, err := NewDefaultHttpClient(HttpClientOptions{Credentials: []HostCredentials{
+			{Host: serverUrl.Hostname(), Username: "user", Password: "secret"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+
+		username, password, ok := get(client, *serverUrl).BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("user"))
+		Expect(password).To(Equal("secret"))
+
+		Expect(get(client, *otherHostUrl).Header.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("should not send the credentials to the host a redirect leads to", func() {
+		client, _ := NewDefaultHttpClient(HttpClientOptions{Credentials: []HostCredentials{
+			{Host: serverUrl.Host, Token: "token"},
+		}})
+
+		redirect := serverUrl.ResolveReference(&url.URL{Path: "/redirect"})
+		request := get(client, *redirect)
+
+		Expect(request.Host).To(Equal(otherHostUrl.Host))
+		Expect(request.Header.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("should send bearer tokens", func() {
+		client, _ := NewDefaultHttpClient(HttpClientOptions{Credentials: []HostCredentials{
+			{Host: serverUrl.Host, Token: "token"},
+		}})
+
+		Expect(get(client, *serverUrl).Header.Get("Authorization")).To(Equal("Bearer token"))
+	})
+
+	It("should keep the session cookies set by the login form", func() {
+		client, _ := NewDefaultHttpClient(HttpClientOptions{Cookies: true})
+		login := serverUrl.ResolveReference(&url.URL{Path: "/login"})
+
+		Expect(client.Login(*login, url.Values{"username": {"user"}, "password": {"wrong"}})).
+			To(MatchError(ContainSubstring("answered 403")))
+
+		Expect(client.Login(*login, url.Values{"username": {"user"}, "password": {"secret"}})).To(Succeed())
+		<-requests
+
+		cookie, err := get(client, *serverUrl).Cookie("session")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cookie.Value).To(Equal("42"))
+	})
+
+	It("should refuse to log in without cookies", func() {
+		client, _ := NewDefaultHttpClient(HttpClientOptions{})
+
+		Expect(client.Login(*serverUrl, url.Values{})).NotTo(Succeed())
+	})
+
+})
+
+var _ = Describe("ParseBasicCredentials", func() {
+
+	It("should parse host=user:password", func() {
+		credentials, err := ParseBasicCredentials("docs.google.com=me:pass:word")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(HostCredentials{Host: "docs.google.com", Username: "me", Password: "pass:word"}))
+
+		_, err = ParseBasicCredentials("docs.google.com=me")
+		Expect(err).To(HaveOccurred())
+	})
+
+})
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"net/url"
+)
+
+// Minimal Bloom filter: may answer "maybe" for addresses it never saw,
+// never answers "no" for addresses it did see
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// Sizes the filter for the expected number of items and the accepted
+// false positive rate (e.g. 0.01)
+func NewBloomFilter(expectedItems int, falsePositiveRate float64) *BloomFilter {
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+
+	// usual formulas for the optimal number of bits and hash functions
+	n := float64(expectedItems)
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+
+	size := uint64(m)
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: uint64(k),
+	}
+}
+
+// double hashing (Kirsch-Mitzenmacher) over two fnv hashes, saves us from
+// computing k independent hashes
+func (filter *BloomFilter) positions(key string) []uint64 {
+	h1 := fnv.New64()
+	h1.Write([]byte(key))
+	h2 := fnv.New64a()
+	h2.Write([]byte(key))
+	a, b := h1.Sum64(), h2.Sum64()|1
+
+	positions := make([]uint64, filter.hashes)
+	for i := uint64(0); i < filter.hashes; i++ {
+		positions[i] = (a + i*b) % filter.size
+	}
+	return positions
+}
+
+func (filter *BloomFilter) Add(key string) {
+	for _, pos := range filter.positions(key) {
+		filter.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (filter *BloomFilter) MayContain(key string) bool {
+	for _, pos := range filter.positions(key) {
+		if filter.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SeenSet backed by a Bloom filter. When a backing set is given the filter
+// only saves lookups on it (answers stay exact), without one the set is
+// probabilistic and a small fraction of pages will be wrongly skipped
+type BloomSeenSet struct {
+	filter  *BloomFilter
+	backing SeenSet
+}
+
+func NewBloomSeenSet(filter *BloomFilter, backing SeenSet) *BloomSeenSet {
+	return &BloomSeenSet{filter, backing}
+}
+
+func (set *BloomSeenSet) Add(address url.URL) error {
+	set.filter.Add(address.String())
+	if set.backing != nil {
+		return set.backing.Add(address)
+	}
+	return nil
+}
+
+func (set *BloomSeenSet) Contains(address url.URL) (bool, error) {
+	if !set.filter.MayContain(address.String()) {
+		return false, nil
+	}
+	if set.backing != nil {
+		return set.backing.Contains(address)
+	}
+	return true, nil
+}
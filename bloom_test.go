@@ -0,0 +1,44 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/url"
+)
+
+var _ = Describe("BloomSeenSet", func() {
+
+	var (
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+	})
+
+	It("should always report added addresses", func() {
+		set := NewBloomSeenSet(NewBloomFilter(100, 0.01), nil)
+
+		set.Add(*pageUrl)
+
+		found, _ := set.Contains(*pageUrl)
+		Expect(found).To(BeTrue())
+	})
+
+	It("should defer to the backing set when the filter is not sure", func() {
+		backing := make(MemorySeenSet)
+		set := NewBloomSeenSet(NewBloomFilter(100, 0.01), backing)
+
+		set.Add(*pageUrl)
+
+		found, _ := set.Contains(*pageUrl)
+		Expect(found).To(BeTrue())
+		found, _ = set.Contains(*aboutPageUrl)
+		Expect(found).To(BeFalse())
+		Expect(backing).To(HaveKey(*pageUrl))
+	})
+
+})
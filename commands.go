@@ -0,0 +1,313 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Commands other than crawling, invoked as "sitemapper <command> [args]"
+var commands = map[string]func(args []string){
+	"cache":      cacheCommand,
+	"diff":       diffCommand,
+	"report":     reportCommand,
+	"path":       pathCommand,
+	"structure":  structureCommand,
+	"audit":      auditCommand,
+	"duplicates": duplicatesCommand,
+	"timings":    timingsCommand,
+}
+
+// Runs the command named by the first argument, returns false if there is none
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	command, found := commands[args[0]]
+	if !found {
+		return false
+	}
+	command(args[1:])
+	return true
+}
+
+// sitemapper cache clear|prune [-dir DIR] [-ttl TTL]
+func cacheCommand(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: sitemapper cache clear|prune [options]")
+	}
+
+	flags := flag.NewFlagSet("cache "+args[0], flag.ExitOnError)
+	dir := flags.String("dir", ".sitemapper-cache", "cache directory")
+	ttl := flags.Duration("ttl", 24*time.Hour, "prune removes the entries older than this")
+	flags.Parse(args[1:])
+
+	var removed int
+	var err error
+	switch args[0] {
+	case "clear":
+		removed, err = ClearHttpCache(*dir, 0)
+	case "prune":
+		removed, err = ClearHttpCache(*dir, *ttl)
+	default:
+		log.Fatal("Unknown cache command ", args[0])
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal("Can't clear cache ", *dir, " ", err)
+	}
+
+	fmt.Println("Removed", removed, "cache entries from", *dir)
+}
+
+func loadCrawlResult(path string) *CrawlResult {
+	result, err := LoadCrawlResult(path)
+	if err != nil {
+		log.Fatal("Can't load crawl result ", path, " ", err)
+	}
+	return result
+}
+
+// sitemapper diff [-format text|json] BEFORE AFTER
+func diffCommand(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		log.Fatal("Usage: sitemapper diff [options] BEFORE.json AFTER.json")
+	}
+
+	diff := DiffCrawls(loadCrawlResult(flags.Arg(0)), loadCrawlResult(flags.Arg(1)))
+
+	switch *format {
+	case "text":
+		diff.WriteText(os.Stdout)
+	case "json":
+		if err := diff.WriteJson(os.Stdout); err != nil {
+			log.Fatal("Can't write diff ", err)
+		}
+	default:
+		log.Fatal("Unknown format ", *format)
+	}
+}
+
+func loadBrokenPages(result *CrawlResult) map[url.URL]bool {
+	broken, err := result.BrokenPages()
+	if err != nil {
+		log.Fatal("Can't read crawl result ", err)
+	}
+	return broken
+}
+
+func loadPagesMap(result *CrawlResult) (url.URL, PagesMap) {
+	root, err := result.RootUrl()
+	if err != nil {
+		log.Fatal("Can't parse root ", result.Root, " ", err)
+	}
+	pages, err := result.PagesMap()
+	if err != nil {
+		log.Fatal("Can't read crawl result ", err)
+	}
+	return root, pages
+}
+
+// sitemapper report [-format text|json] [-deep N] CRAWL
+func reportCommand(args []string) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	deep := flags.Int("deep", 4, "pages more than this many clicks away from the root are reported as deep")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: sitemapper report [options] CRAWL.json")
+	}
+
+	result := loadCrawlResult(flags.Arg(0))
+	root, pages := loadPagesMap(result)
+	report := AnalyzeLinks(pages, loadBrokenPages(result), root, *deep)
+
+	switch *format {
+	case "text":
+		report.WriteText(os.Stdout)
+	case "json":
+		if err := report.WriteJson(os.Stdout); err != nil {
+			log.Fatal("Can't write report ", err)
+		}
+	default:
+		log.Fatal("Unknown format ", *format)
+	}
+}
+
+// sitemapper path [-max N] CRAWL FROM TO
+func pathCommand(args []string) {
+	flags := flag.NewFlagSet("path", flag.ExitOnError)
+	maxPaths := flags.Int("max", 3, "maximum number of shortest paths printed")
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		log.Fatal("Usage: sitemapper path [options] CRAWL.json FROM TO")
+	}
+	if *maxPaths < 1 {
+		log.Fatal("-max must be at least 1")
+	}
+
+	root, pages := loadPagesMap(loadCrawlResult(flags.Arg(0)))
+
+	// addresses may be given relative to the root (e.g. / and /about)
+	resolve := func(raw string) url.URL {
+		address, err := url.Parse(raw)
+		if err != nil {
+			log.Fatal("Can't parse address ", raw)
+		}
+		return *root.ResolveReference(address)
+	}
+	from := resolve(flags.Arg(1))
+	to := resolve(flags.Arg(2))
+
+	WritePaths(os.Stdout, from, to, ShortestPaths(pages, from, to, *maxPaths))
+}
+
+// sitemapper structure [-format text|json] [-section-depth N] CRAWL
+func structureCommand(args []string) {
+	flags := flag.NewFlagSet("structure", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	sectionDepth := flags.Int("section-depth", 1, "number of path segments defining a section")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: sitemapper structure [options] CRAWL.json")
+	}
+	if *sectionDepth < 1 {
+		log.Fatal("-section-depth must be at least 1")
+	}
+
+	result := loadCrawlResult(flags.Arg(0))
+	root, pages := loadPagesMap(result)
+	report := AnalyzeStructure(pages, loadBrokenPages(result), root, *sectionDepth)
+
+	switch *format {
+	case "text":
+		report.WriteText(os.Stdout)
+	case "json":
+		if err := report.WriteJson(os.Stdout); err != nil {
+			log.Fatal("Can't write report ", err)
+		}
+	default:
+		log.Fatal("Unknown format ", *format)
+	}
+}
+
+// sitemapper audit [-format text|json|html] [-max-url-length N] [-min-words N] CRAWL
+func auditCommand(args []string) {
+	defaults := DefaultAuditOptions()
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or html")
+	maxUrlLength := flags.Int("max-url-length", defaults.MaxUrlLength, "addresses longer than this are reported")
+	minWords := flags.Int("min-words", defaults.MinWordCount, "pages with less words than this are reported as thin")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: sitemapper audit [options] CRAWL.json")
+	}
+
+	result := loadCrawlResult(flags.Arg(0))
+	root, err := result.RootUrl()
+	if err != nil {
+		log.Fatal("Can't parse root ", result.Root, " ", err)
+	}
+	store, err := result.Store()
+	if err != nil {
+		log.Fatal("Can't read crawl result ", err)
+	}
+
+	report, err := AuditCrawl(root, store, AuditOptions{*maxUrlLength, *minWords})
+	if err != nil {
+		log.Fatal("Can't audit crawl ", err)
+	}
+
+	switch *format {
+	case "text":
+		report.WriteText(os.Stdout)
+	case "json":
+		err = report.WriteJson(os.Stdout)
+	case "html":
+		err = report.WriteHtml(os.Stdout)
+	default:
+		log.Fatal("Unknown format ", *format)
+	}
+	if err != nil {
+		log.Fatal("Can't write report ", err)
+	}
+}
+
+// sitemapper duplicates [-format text|json] [-distance N] CRAWL
+func duplicatesCommand(args []string) {
+	flags := flag.NewFlagSet("duplicates", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	distance := flags.Int("distance", DefaultNearDuplicateDistance,
+		"pages whose fingerprints differ in at most this many bits are near duplicates")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: sitemapper duplicates [options] CRAWL.json")
+	}
+
+	store, err := loadCrawlResult(flags.Arg(0)).Store()
+	if err != nil {
+		log.Fatal("Can't read crawl result ", err)
+	}
+	clusters, err := FindDuplicates(store, *distance)
+	if err != nil {
+		log.Fatal("Can't find duplicates ", err)
+	}
+
+	switch *format {
+	case "text":
+		WriteDuplicatesText(os.Stdout, clusters)
+	case "json":
+		if err := WriteDuplicatesJson(os.Stdout, clusters); err != nil {
+			log.Fatal("Can't write report ", err)
+		}
+	default:
+		log.Fatal("Unknown format ", *format)
+	}
+}
+
+// sitemapper timings [-format text|json] [-slowest N] CRAWL
+func timingsCommand(args []string) {
+	flags := flag.NewFlagSet("timings", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	slowest := flags.Int("slowest", 10, "number of slowest pages listed")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: sitemapper timings [options] CRAWL.json")
+	}
+	if *slowest < 0 {
+		log.Fatal("-slowest can't be negative")
+	}
+
+	store, err := loadCrawlResult(flags.Arg(0)).Store()
+	if err != nil {
+		log.Fatal("Can't read crawl result ", err)
+	}
+	report, err := AnalyzeTimings(store, *slowest)
+	if err != nil {
+		log.Fatal("Can't analyze timings ", err)
+	}
+
+	switch *format {
+	case "text":
+		report.WriteText(os.Stdout)
+	case "json":
+		if err := report.WriteJson(os.Stdout); err != nil {
+			log.Fatal("Can't write report ", err)
+		}
+	default:
+		log.Fatal("Unknown format ", *format)
+	}
+}
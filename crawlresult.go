@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/url"
+	"os"
+)
+
+// A retrieved page as it is saved on disk, addresses in their string form
+type PageRecord struct {
+	Address      string   `json:"address"`
+	Links        []string `json:"links"`
+	StatusCode   int      `json:"status_code,omitempty"`
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	NotModified  bool     `json:"not_modified,omitempty"`
+	RedirectedTo string   `json:"redirected_to,omitempty"`
+	Info         PageInfo `json:"info"`
+	// how long the request took, if measured
+	Timing *RequestTiming `json:"timing,omitempty"`
+	// see ResponseInfo.StatusOnly
+	StatusOnly bool `json:"status_only,omitempty"`
+}
+
+func NewPageRecord(page HtmlPageLinks) PageRecord {
+	links := make([]string, len(page.LinksTo))
+	for i, link := range page.LinksTo {
+		links[i] = link.String()
+	}
+	return PageRecord{
+		Address:      page.Address.String(),
+		Links:        links,
+		StatusCode:   page.Response.StatusCode,
+		ETag:         page.Response.ETag,
+		LastModified: page.Response.LastModified,
+		NotModified:  page.Response.NotModified,
+		RedirectedTo: page.Response.RedirectedTo,
+		Info:         page.Info,
+		Timing:       page.Response.Timing,
+		StatusOnly:   page.Response.StatusOnly,
+	}
+}
+
+func (record PageRecord) ToPage() (HtmlPageLinks, error) {
+	address, err := url.Parse(record.Address)
+	if err != nil {
+		return HtmlPageLinks{}, err
+	}
+	links := make([]url.URL, 0, len(record.Links))
+	for _, raw := range record.Links {
+		link, err := url.Parse(raw)
+		if err != nil {
+			return HtmlPageLinks{}, err
+		}
+		links = append(links, *link)
+	}
+	return HtmlPageLinks{
+		Address: *address,
+		LinksTo: links,
+		Response: ResponseInfo{
+			StatusCode:   record.StatusCode,
+			ETag:         record.ETag,
+			LastModified: record.LastModified,
+			NotModified:  record.NotModified,
+			RedirectedTo: record.RedirectedTo,
+			Timing:       record.Timing,
+			StatusOnly:   record.StatusOnly,
+		},
+		Info: record.Info,
+	}, nil
+}
+
+// The outcome of a crawl as saved with -save, used by later runs and by the
+// commands working on previous crawls
+type CrawlResult struct {
+	Root  string       `json:"root"`
+	Pages []PageRecord `json:"pages"`
+	// pages serving the same or almost the same content, as found by FindDuplicates
+	Duplicates []DuplicateCluster `json:"duplicates,omitempty"`
+}
+
+// Writes the content of the store to the given path, along with the clusters of
+// duplicate pages. Pages are encoded one at a time so a disk backed store is
+// never loaded in memory as a whole
+func SaveCrawlResult(path string, root url.URL, store PageStore) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	rootJson, err := json.Marshal(root.String())
+	if err != nil {
+		return err
+	}
+	writer.WriteString(`{"root":`)
+	writer.Write(rootJson)
+	writer.WriteString(`,"pages":[`)
+
+	first := true
+	err = store.Each(func(page HtmlPageLinks) error {
+		record, err := json.Marshal(NewPageRecord(page))
+		if err != nil {
+			return err
+		}
+		if !first {
+			writer.WriteString(",\n")
+		}
+		first = false
+		_, err = writer.Write(record)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	duplicates, err := FindDuplicates(store, DefaultNearDuplicateDistance)
+	if err != nil {
+		return err
+	}
+	duplicatesJson, err := json.Marshal(duplicates)
+	if err != nil {
+		return err
+	}
+	writer.WriteString(`],"duplicates":`)
+	writer.Write(duplicatesJson)
+
+	writer.WriteString("}\n")
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func LoadCrawlResult(path string) (*CrawlResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var result CrawlResult
+	if err := json.NewDecoder(bufio.NewReader(file)).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Loads the saved pages in an in memory PageStore
+func (result *CrawlResult) Store() (MemoryPageStore, error) {
+	store := make(MemoryPageStore, len(result.Pages))
+	for _, record := range result.Pages {
+		page, err := record.ToPage()
+		if err != nil {
+			return nil, err
+		}
+		store.Put(page)
+	}
+	return store, nil
+}
+
+// The saved link graph, as returned by MapSite
+func (result *CrawlResult) PagesMap() (PagesMap, error) {
+	store, err := result.Store()
+	if err != nil {
+		return nil, err
+	}
+	return ToPagesMap(store)
+}
+
+// The pages that could not be fetched or answered with an error, they have no
+// links of their own
+func (result *CrawlResult) BrokenPages() (map[url.URL]bool, error) {
+	broken := make(map[url.URL]bool)
+	for _, record := range result.Pages {
+		if !isBrokenStatus(record.StatusCode) {
+			continue
+		}
+		address, err := url.Parse(record.Address)
+		if err != nil {
+			return nil, err
+		}
+		broken[*address] = true
+	}
+	return broken, nil
+}
+
+func (result *CrawlResult) RootUrl() (url.URL, error) {
+	root, err := url.Parse(result.Root)
+	if err != nil {
+		return url.URL{}, err
+	}
+	return *root, nil
+}
@@ -0,0 +1,91 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ = Describe("CrawlResult", func() {
+
+	var (
+		dir string
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+
+		var err error
+		dir, err = ioutil.TempDir("", "sitemapper")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should load what was saved", func() {
+		pages := make(MemoryPageStore)
+		pages.Put(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl},
+			Response: ResponseInfo{
+				StatusCode: 200,
+				ETag: `"v1"`,
+				LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+				Timing: &RequestTiming{DNS: time.Millisecond, FirstByte: 20 * time.Millisecond, Total: 30 * time.Millisecond, Bytes: 512},
+			},
+		})
+		pages.Put(HtmlPageLinks{
+			Address: *aboutPageUrl,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 404},
+		})
+
+		path := filepath.Join(dir, "crawl.json")
+		Expect(SaveCrawlResult(path, *pageUrl, pages)).To(Succeed())
+
+		result, err := LoadCrawlResult(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Root).To(Equal(pageUrl.String()))
+		Expect(result.Duplicates).To(BeEmpty())
+
+		loaded, err := result.Store()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(pages))
+
+		broken, err := result.BrokenPages()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(broken).To(Equal(map[url.URL]bool{*aboutPageUrl: true}))
+	})
+
+	It("should save the clusters of duplicate pages", func() {
+		pages := make(MemoryPageStore)
+		for _, address := range []*url.URL{pageUrl, aboutPageUrl} {
+			pages.Put(HtmlPageLinks{
+				Address: *address,
+				LinksTo: []url.URL{},
+				Response: ResponseInfo{StatusCode: 200},
+				Info: PageInfo{WordCount: 2, ContentHash: "same", SimHash: 42},
+			})
+		}
+
+		path := filepath.Join(dir, "crawl.json")
+		Expect(SaveCrawlResult(path, *pageUrl, pages)).To(Succeed())
+
+		result, err := LoadCrawlResult(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Duplicates).To(Equal([]DuplicateCluster{
+			{ExactDuplicate, []string{pageUrl.String(), aboutPageUrl.String()}},
+		}))
+	})
+
+})
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A link from a page to another, addresses in their string form
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type StatusChange struct {
+	Address string `json:"address"`
+	Before  int    `json:"before"`
+	After   int    `json:"after"`
+}
+
+// A link pointing to a page that could not be fetched or answered with an error status
+type BrokenLink struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	StatusCode int    `json:"status_code"`
+}
+
+// What changed on the site between two crawls
+type CrawlDiff struct {
+	AddedPages     []string       `json:"added_pages"`
+	RemovedPages   []string       `json:"removed_pages"`
+	StatusChanges  []StatusChange `json:"status_changes"`
+	NewBrokenLinks []BrokenLink   `json:"new_broken_links"`
+	AddedEdges     []Edge         `json:"added_edges"`
+	RemovedEdges   []Edge         `json:"removed_edges"`
+}
+
+// 0 means the page could not be fetched at all
+func isBrokenStatus(statusCode int) bool {
+	return statusCode == 0 || statusCode >= 400
+}
+
+// the pages reached following links
+func indexRecords(result *CrawlResult) map[string]PageRecord {
+	records := make(map[string]PageRecord, len(result.Pages))
+	for _, record := range result.Pages {
+		if !record.StatusOnly {
+			records[record.Address] = record
+		}
+	}
+	return records
+}
+
+func indexEdges(records map[string]PageRecord) map[Edge]bool {
+	edges := make(map[Edge]bool)
+	for address, record := range records {
+		for _, link := range record.Links {
+			edges[Edge{address, link}] = true
+		}
+	}
+	return edges
+}
+
+// the links to pages that were crawled and turned out broken (links leaving
+// the crawled site were never fetched, we can't tell)
+func brokenLinks(records map[string]PageRecord, edges map[Edge]bool) map[Edge]int {
+	broken := make(map[Edge]int)
+	for edge := range edges {
+		target, crawled := records[edge.To]
+		if crawled && isBrokenStatus(target.StatusCode) {
+			broken[edge] = target.StatusCode
+		}
+	}
+	return broken
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
+
+// Compares two crawls, usually of the same site at different times
+func DiffCrawls(before *CrawlResult, after *CrawlResult) CrawlDiff {
+	beforeRecords := indexRecords(before)
+	afterRecords := indexRecords(after)
+
+	diff := CrawlDiff{
+		AddedPages:     make([]string, 0),
+		RemovedPages:   make([]string, 0),
+		StatusChanges:  make([]StatusChange, 0),
+		NewBrokenLinks: make([]BrokenLink, 0),
+		AddedEdges:     make([]Edge, 0),
+		RemovedEdges:   make([]Edge, 0),
+	}
+
+	for address, record := range afterRecords {
+		previous, found := beforeRecords[address]
+		if !found {
+			diff.AddedPages = append(diff.AddedPages, address)
+		} else if previous.StatusCode != record.StatusCode {
+			diff.StatusChanges = append(diff.StatusChanges, StatusChange{address, previous.StatusCode, record.StatusCode})
+		}
+	}
+	for address := range beforeRecords {
+		if _, found := afterRecords[address]; !found {
+			diff.RemovedPages = append(diff.RemovedPages, address)
+		}
+	}
+
+	beforeEdges := indexEdges(beforeRecords)
+	afterEdges := indexEdges(afterRecords)
+	for edge := range afterEdges {
+		if !beforeEdges[edge] {
+			diff.AddedEdges = append(diff.AddedEdges, edge)
+		}
+	}
+	for edge := range beforeEdges {
+		if !afterEdges[edge] {
+			diff.RemovedEdges = append(diff.RemovedEdges, edge)
+		}
+	}
+
+	beforeBroken := brokenLinks(beforeRecords, beforeEdges)
+	for edge, statusCode := range brokenLinks(afterRecords, afterEdges) {
+		if _, wasBroken := beforeBroken[edge]; !wasBroken {
+			diff.NewBrokenLinks = append(diff.NewBrokenLinks, BrokenLink{edge.From, edge.To, statusCode})
+		}
+	}
+
+	// maps have no order, sort everything to get stable reports
+	sort.Strings(diff.AddedPages)
+	sort.Strings(diff.RemovedPages)
+	sort.Slice(diff.StatusChanges, func(i, j int) bool {
+		return diff.StatusChanges[i].Address < diff.StatusChanges[j].Address
+	})
+	sort.Slice(diff.NewBrokenLinks, func(i, j int) bool {
+		if diff.NewBrokenLinks[i].From != diff.NewBrokenLinks[j].From {
+			return diff.NewBrokenLinks[i].From < diff.NewBrokenLinks[j].From
+		}
+		return diff.NewBrokenLinks[i].To < diff.NewBrokenLinks[j].To
+	})
+	sortEdges(diff.AddedEdges)
+	sortEdges(diff.RemovedEdges)
+
+	return diff
+}
+
+func (diff CrawlDiff) IsEmpty() bool {
+	return len(diff.AddedPages) == 0 && len(diff.RemovedPages) == 0 &&
+		len(diff.StatusChanges) == 0 && len(diff.NewBrokenLinks) == 0 &&
+		len(diff.AddedEdges) == 0 && len(diff.RemovedEdges) == 0
+}
+
+func (diff CrawlDiff) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diff)
+}
+
+func (diff CrawlDiff) WriteText(w io.Writer) {
+	if diff.IsEmpty() {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+
+	section := func(title string, count int) bool {
+		if count > 0 {
+			fmt.Fprintf(w, "%s (%d)\n", title, count)
+		}
+		return count > 0
+	}
+
+	if section("Added pages", len(diff.AddedPages)) {
+		for _, address := range diff.AddedPages {
+			fmt.Fprintln(w, "  +", address)
+		}
+	}
+	if section("Removed pages", len(diff.RemovedPages)) {
+		for _, address := range diff.RemovedPages {
+			fmt.Fprintln(w, "  -", address)
+		}
+	}
+	if section("Changed status codes", len(diff.StatusChanges)) {
+		for _, change := range diff.StatusChanges {
+			fmt.Fprintf(w, "  %s %d -> %d\n", change.Address, change.Before, change.After)
+		}
+	}
+	if section("New broken links", len(diff.NewBrokenLinks)) {
+		for _, link := range diff.NewBrokenLinks {
+			fmt.Fprintf(w, "  %s -> %s (%d)\n", link.From, link.To, link.StatusCode)
+		}
+	}
+	if section("Added links", len(diff.AddedEdges)) {
+		for _, edge := range diff.AddedEdges {
+			fmt.Fprintf(w, "  + %s -> %s\n", edge.From, edge.To)
+		}
+	}
+	if section("Removed links", len(diff.RemovedEdges)) {
+		for _, edge := range diff.RemovedEdges {
+			fmt.Fprintf(w, "  - %s -> %s\n", edge.From, edge.To)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"encoding/json"
+)
+
+var _ = Describe("DiffCrawls", func() {
+
+	var (
+		before *CrawlResult
+		after *CrawlResult
+	)
+
+	BeforeEach(func() {
+		before = &CrawlResult{
+			Root: "https://www.google.com/",
+			Pages: []PageRecord{
+				{Address: "https://www.google.com/", Links: []string{"https://www.google.com/about", "https://www.google.com/old"}, StatusCode: 200},
+				{Address: "https://www.google.com/about", Links: []string{}, StatusCode: 200},
+				{Address: "https://www.google.com/old", Links: []string{}, StatusCode: 200},
+			},
+		}
+		after = &CrawlResult{
+			Root: "https://www.google.com/",
+			Pages: []PageRecord{
+				{Address: "https://www.google.com/", Links: []string{"https://www.google.com/about", "https://www.google.com/new"}, StatusCode: 200},
+				{Address: "https://www.google.com/about", Links: []string{}, StatusCode: 404},
+				{Address: "https://www.google.com/new", Links: []string{}, StatusCode: 200},
+			},
+		}
+	})
+
+	It("should report what changed between the two crawls", func() {
+		diff := DiffCrawls(before, after)
+
+		Expect(diff.AddedPages).To(Equal([]string{"https://www.google.com/new"}))
+		Expect(diff.RemovedPages).To(Equal([]string{"https://www.google.com/old"}))
+		Expect(diff.StatusChanges).To(Equal([]StatusChange{
+			{"https://www.google.com/about", 200, 404},
+		}))
+		Expect(diff.NewBrokenLinks).To(Equal([]BrokenLink{
+			{"https://www.google.com/", "https://www.google.com/about", 404},
+		}))
+		Expect(diff.AddedEdges).To(Equal([]Edge{
+			{"https://www.google.com/", "https://www.google.com/new"},
+		}))
+		Expect(diff.RemovedEdges).To(Equal([]Edge{
+			{"https://www.google.com/", "https://www.google.com/old"},
+		}))
+	})
+
+	It("should report nothing for identical crawls", func() {
+		diff := DiffCrawls(before, before)
+
+		Expect(diff.IsEmpty()).To(BeTrue())
+
+		var text bytes.Buffer
+		diff.WriteText(&text)
+		Expect(text.String()).To(Equal("No changes\n"))
+	})
+
+	It("should write valid json", func() {
+		var out bytes.Buffer
+		Expect(DiffCrawls(before, after).WriteJson(&out)).To(Succeed())
+
+		var decoded CrawlDiff
+		Expect(json.Unmarshal(out.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded.AddedPages).To(HaveLen(1))
+	})
+
+})
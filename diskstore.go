@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/url"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	frontierBucket = []byte("frontier")
+	seenBucket     = []byte("seen")
+	pagesBucket    = []byte("pages")
+)
+
+// Embedded on disk storage for the mapper's state, one bolt file holding
+// the frontier, the seen set and the retrieved pages. Addresses are stored
+// in their string form
+type DiskStore struct {
+	db       *bolt.DB
+	frontier int
+	pages    int
+}
+
+// Opens (or creates) the store at the given path, any content left by a
+// previous crawl is discarded. Since nothing survives a restart anyway the
+// writes are not synced to disk, each one would cost an fsync otherwise
+func OpenDiskStore(path string) (*DiskStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{NoSync: true, NoFreelistSync: true})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{frontierBucket, seenBucket, pagesBucket} {
+			if tx.Bucket(name) != nil {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &DiskStore{db: db}, nil
+}
+
+func (store *DiskStore) Close() error {
+	return store.db.Close()
+}
+
+// Entries are sorted by the given Ordering (FIFO if nil), as the in memory
+// frontier does
+func (store *DiskStore) Frontier(ordering Ordering) Frontier {
+	if ordering == nil {
+		ordering = FifoOrdering
+	}
+	return &diskFrontier{store, ordering}
+}
+
+func (store *DiskStore) Seen() SeenSet {
+	return &diskSeenSet{store}
+}
+
+func (store *DiskStore) Pages() PageStore {
+	return &diskPageStore{store}
+}
+
+// Keys are the ordering key followed by the bucket sequence, bolt keeps them
+// sorted so the cursor's first entry is the next one to pop
+type diskFrontier struct {
+	store    *DiskStore
+	ordering Ordering
+}
+
+type diskFrontierValue struct {
+	Address string
+	Depth   int
+}
+
+func (frontier *diskFrontier) Push(entry FrontierEntry) error {
+	value, err := json.Marshal(diskFrontierValue{entry.Address.String(), entry.Depth})
+	if err != nil {
+		return err
+	}
+	err = frontier.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(frontierBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 16)
+		// flipping the sign bit makes negative keys sort before positive ones
+		binary.BigEndian.PutUint64(key, uint64(frontier.ordering(entry, seq))^(1<<63))
+		binary.BigEndian.PutUint64(key[8:], seq)
+		return bucket.Put(key, value)
+	})
+	if err == nil {
+		frontier.store.frontier++
+	}
+	return err
+}
+
+func (frontier *diskFrontier) Pop() (FrontierEntry, bool, error) {
+	var value []byte
+	err := frontier.store.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(frontierBucket).Cursor()
+		key, found := cursor.First()
+		if key == nil {
+			return nil
+		}
+		// bolt values are only valid inside the transaction
+		value = append([]byte{}, found...)
+		return cursor.Delete()
+	})
+	if err != nil || value == nil {
+		return FrontierEntry{}, false, err
+	}
+
+	frontier.store.frontier--
+	var decoded diskFrontierValue
+	if err := json.Unmarshal(value, &decoded); err != nil {
+		return FrontierEntry{}, false, err
+	}
+	address, err := url.Parse(decoded.Address)
+	if err != nil {
+		return FrontierEntry{}, false, err
+	}
+	return FrontierEntry{*address, decoded.Depth}, true, nil
+}
+
+func (frontier *diskFrontier) Len() int {
+	return frontier.store.frontier
+}
+
+type diskSeenSet struct {
+	store *DiskStore
+}
+
+func (set *diskSeenSet) Add(address url.URL) error {
+	return set.store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(seenBucket).Put([]byte(address.String()), []byte{})
+	})
+}
+
+func (set *diskSeenSet) Contains(address url.URL) (bool, error) {
+	found := false
+	err := set.store.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(seenBucket).Get([]byte(address.String())) != nil
+		return nil
+	})
+	return found, err
+}
+
+// pages are stored as json encoded PageRecords
+type diskPageStore struct {
+	store *DiskStore
+}
+
+func decodePage(value []byte) (HtmlPageLinks, error) {
+	var record PageRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return HtmlPageLinks{}, err
+	}
+	return record.ToPage()
+}
+
+func (pages *diskPageStore) Put(page HtmlPageLinks) error {
+	value, err := json.Marshal(NewPageRecord(page))
+	if err != nil {
+		return err
+	}
+	isNew := false
+	err = pages.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pagesBucket)
+		key := []byte(page.Address.String())
+		isNew = bucket.Get(key) == nil
+		return bucket.Put(key, value)
+	})
+	if err == nil && isNew {
+		pages.store.pages++
+	}
+	return err
+}
+
+func (pages *diskPageStore) Get(address url.URL) (HtmlPageLinks, bool, error) {
+	var value []byte
+	err := pages.store.db.View(func(tx *bolt.Tx) error {
+		// bolt values are only valid inside the transaction
+		if found := tx.Bucket(pagesBucket).Get([]byte(address.String())); found != nil {
+			value = append([]byte{}, found...)
+		}
+		return nil
+	})
+	if err != nil || value == nil {
+		return HtmlPageLinks{}, false, err
+	}
+	page, err := decodePage(value)
+	return page, err == nil, err
+}
+
+func (pages *diskPageStore) Len() int {
+	return pages.store.pages
+}
+
+func (pages *diskPageStore) Each(fn func(page HtmlPageLinks) error) error {
+	return pages.store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pagesBucket).ForEach(func(key, value []byte) error {
+			page, err := decodePage(value)
+			if err != nil {
+				return err
+			}
+			return fn(page)
+		})
+	})
+}
@@ -0,0 +1,92 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("DiskStore", func() {
+
+	var (
+		dir string
+		store *DiskStore
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+
+		var err error
+		dir, err = ioutil.TempDir("", "sitemapper")
+		Expect(err).NotTo(HaveOccurred())
+		store, err = OpenDiskStore(filepath.Join(dir, "state.db"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		store.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("should keep the frontier in order", func() {
+		frontier := store.Frontier(nil)
+
+		frontier.Push(FrontierEntry{*pageUrl, 0})
+		frontier.Push(FrontierEntry{*aboutPageUrl, 1})
+		Expect(frontier.Len()).To(Equal(2))
+
+		first, ok, err := frontier.Pop()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(first.Address.String()).To(Equal(pageUrl.String()))
+
+		second, _, _ := frontier.Pop()
+		Expect(second.Address.String()).To(Equal(aboutPageUrl.String()))
+
+		_, ok, _ = frontier.Pop()
+		Expect(ok).To(BeFalse())
+		Expect(frontier.Len()).To(Equal(0))
+	})
+
+	It("should remember seen addresses", func() {
+		seen := store.Seen()
+
+		seen.Add(*pageUrl)
+
+		found, _ := seen.Contains(*pageUrl)
+		Expect(found).To(BeTrue())
+		found, _ = seen.Contains(*aboutPageUrl)
+		Expect(found).To(BeFalse())
+	})
+
+	It("should store pages and their links", func() {
+		pages := store.Pages()
+
+		Expect(pages.Put(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl},
+			Response: ResponseInfo{StatusCode: 200, ETag: `"v1"`},
+		})).To(Succeed())
+		Expect(pages.Put(HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{}})).To(Succeed())
+		Expect(pages.Len()).To(Equal(2))
+
+		page, found, err := pages.Get(*pageUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(page.LinksTo).To(HaveLen(1))
+		Expect(page.LinksTo[0].String()).To(Equal(aboutPageUrl.String()))
+		Expect(page.Response.ETag).To(Equal(`"v1"`))
+
+		res, err := ToPagesMap(pages)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(HaveLen(2))
+	})
+
+})
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/bits"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Fingerprints differing in at most this many bits are near duplicates
+const DefaultNearDuplicateDistance = 3
+
+const (
+	ExactDuplicate = "exact"
+	NearDuplicate  = "near"
+)
+
+// 64 bit SimHash of the given words, computed over overlapping sequences of
+// three words: pages sharing most of their text get fingerprints differing
+// in a few bits only. No words give a zero fingerprint
+func SimHash(words []string) uint64 {
+	const shingle = 3
+
+	var weights [64]int
+	feature := func(words []string) {
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.ToLower(strings.Join(words, " "))))
+		sum := hash.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	if len(words) == 0 {
+		return 0
+	}
+	if len(words) < shingle {
+		feature(words)
+	}
+	for i := 0; i+shingle <= len(words); i++ {
+		feature(words[i : i+shingle])
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+	return fingerprint
+}
+
+// Number of bits that differ between two fingerprints
+func HammingDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Pages serving the same content (Kind exact) or almost the same one (Kind
+// near, the fingerprints of the pages differ in a few bits)
+type DuplicateCluster struct {
+	Kind  string   `json:"kind"`
+	Pages []string `json:"pages"`
+}
+
+// Groups the pages fetched successfully by content. Pages with the same
+// content hash form an exact cluster, pages whose SimHash fingerprints
+// differ in at most maxDistance bits are joined in near clusters (transitively,
+// so two pages of a cluster may be further apart). Pages without text are
+// left out, they would all be duplicates of each other
+func FindDuplicates(store PageStore, maxDistance int) ([]DuplicateCluster, error) {
+	if maxDistance < 0 {
+		maxDistance = 0
+	}
+	if maxDistance > 63 {
+		maxDistance = 63
+	}
+
+	// pages grouped by content, each group is compared once
+	byHash := make(map[string][]url.URL)
+	fingerprints := make(map[string]uint64)
+	err := store.Each(func(page HtmlPageLinks) error {
+		if page.Response.StatusOnly || isBrokenStatus(page.Response.StatusCode) || page.Info.WordCount == 0 || page.Info.ContentHash == "" {
+			return nil
+		}
+		byHash[page.Info.ContentHash] = append(byHash[page.Info.ContentHash], page.Address)
+		fingerprints[page.Info.ContentHash] = page.Info.SimHash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]DuplicateCluster, 0)
+	hashes := make([]string, 0, len(byHash))
+	for hash, addresses := range byHash {
+		hashes = append(hashes, hash)
+		if len(addresses) > 1 {
+			clusters = append(clusters, DuplicateCluster{ExactDuplicate, toStrings(addresses)})
+		}
+	}
+	sort.Strings(hashes)
+
+	// union find over the content groups
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	// splitting the fingerprint in maxDistance+1 bands, two fingerprints within
+	// maxDistance bits share at least one band: only those are compared
+	bands := maxDistance + 1
+	for band := 0; band < bands; band++ {
+		low, high := band*64/bands, (band+1)*64/bands
+		mask := (^uint64(0) >> uint(64-(high-low))) << uint(low)
+
+		buckets := make(map[uint64][]int)
+		for i, hash := range hashes {
+			key := fingerprints[hash] & mask
+			for _, other := range buckets[key] {
+				if HammingDistance(fingerprints[hash], fingerprints[hashes[other]]) <= maxDistance {
+					parent[find(i)] = find(other)
+				}
+			}
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	groups := make(map[int][]int)
+	for i := range hashes {
+		groups[find(i)] = append(groups[find(i)], i)
+	}
+	for _, members := range groups {
+		if len(members) < 2 {
+			continue
+		}
+		addresses := make([]url.URL, 0, len(members))
+		for _, member := range members {
+			addresses = append(addresses, byHash[hashes[member]]...)
+		}
+		clusters = append(clusters, DuplicateCluster{NearDuplicate, toStrings(addresses)})
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		a, b := clusters[i], clusters[j]
+		if a.Kind != b.Kind {
+			return a.Kind == ExactDuplicate
+		}
+		if len(a.Pages) != len(b.Pages) {
+			return len(a.Pages) > len(b.Pages)
+		}
+		return a.Pages[0] < b.Pages[0]
+	})
+
+	return clusters, nil
+}
+
+func WriteDuplicatesJson(w io.Writer, clusters []DuplicateCluster) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(clusters)
+}
+
+func WriteDuplicatesText(w io.Writer, clusters []DuplicateCluster) {
+	for _, kind := range []string{ExactDuplicate, NearDuplicate} {
+		count := 0
+		for _, cluster := range clusters {
+			if cluster.Kind == kind {
+				count++
+			}
+		}
+		fmt.Fprintf(w, "%s duplicates (%d clusters)\n", strings.ToUpper(kind[:1])+kind[1:], count)
+		for _, cluster := range clusters {
+			if cluster.Kind != kind {
+				continue
+			}
+			fmt.Fprintf(w, "  %d pages\n", len(cluster.Pages))
+			for _, address := range cluster.Pages {
+				fmt.Fprintln(w, "    ", address)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"net/url"
+	"strings"
+)
+
+var _ = Describe("SimHash", func() {
+
+	text := strings.Fields(`Sitemapper crawls a web site starting from its home page, following the
+		links pointing to pages on the same host, and prints the tree of the pages it found. Results
+		can be saved and compared with later crawls, with the published sitemap or analysed to find
+		pages that are too deep, broken links, duplicated titles and many other common mistakes that
+		make a site harder to navigate for visitors and for search engines alike`)
+
+	It("should give close fingerprints to similar texts", func() {
+		// about the length of a page
+		page := append(append(append([]string{}, text...), text...), text...)
+		similar := append([]string{}, page...)
+		similar[10] = "towards"
+
+		Expect(HammingDistance(SimHash(page), SimHash(similar))).To(BeNumerically("<=", DefaultNearDuplicateDistance))
+	})
+
+	It("should give distant fingerprints to different texts", func() {
+		different := strings.Fields(`The quick brown fox jumps over the lazy dog while the cat sleeps
+			on the sofa and nobody in the house notices anything at all until the morning comes`)
+
+		Expect(HammingDistance(SimHash(text), SimHash(different))).To(BeNumerically(">", DefaultNearDuplicateDistance))
+	})
+
+	It("should ignore the case of the words", func() {
+		Expect(SimHash([]string{"Same", "WORDS"})).To(Equal(SimHash([]string{"same", "words"})))
+		Expect(SimHash([]string{})).To(BeZero())
+	})
+
+})
+
+var _ = Describe("FindDuplicates", func() {
+
+	var (
+		store MemoryPageStore
+	)
+
+	page := func(address string, hash string, simHash uint64, status int) HtmlPageLinks {
+		parsed, _ := url.Parse(address)
+		return HtmlPageLinks{
+			Address: *parsed,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: status},
+			Info: PageInfo{WordCount: 100, ContentHash: hash, SimHash: simHash},
+		}
+	}
+
+	BeforeEach(func() {
+		store = make(MemoryPageStore)
+		store.Put(page("https://www.google.com/", "home", 0xff00ff00, 200))
+		store.Put(page("https://www.google.com/?session=42", "home", 0xff00ff00, 200))
+		store.Put(page("https://www.google.com/about", "about", 0x0000ffff, 200))
+		store.Put(page("https://www.google.com/about/print", "about-print", 0x0000fff7, 200))
+		store.Put(page("https://www.google.com/about/amp", "about-amp", 0x0000ff77, 200))
+		store.Put(page("https://www.google.com/jobs", "jobs", 0xffff0000, 200))
+		store.Put(page("https://www.google.com/broken", "jobs", 0xffff0000, 500))
+	})
+
+	It("should cluster exact and near duplicates", func() {
+		clusters, err := FindDuplicates(store, 1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusters).To(Equal([]DuplicateCluster{
+			{ExactDuplicate, []string{"https://www.google.com/", "https://www.google.com/?session=42"}},
+			// the amp version is near the print one only, it joins the cluster through it
+			{NearDuplicate, []string{
+				"https://www.google.com/about",
+				"https://www.google.com/about/amp",
+				"https://www.google.com/about/print",
+			}},
+		}))
+	})
+
+	It("should only report exact duplicates with a zero distance", func() {
+		clusters, _ := FindDuplicates(store, 0)
+
+		Expect(clusters).To(HaveLen(1))
+		Expect(clusters[0].Kind).To(Equal(ExactDuplicate))
+	})
+
+	It("should ignore the pages requested for their status only", func() {
+		canonical := page("https://www.google.com/jobs/", "jobs", 0xffff0000, 200)
+		canonical.Response.StatusOnly = true
+		store.Put(canonical)
+
+		clusters, _ := FindDuplicates(store, 0)
+
+		Expect(clusters).To(HaveLen(1))
+		Expect(clusters[0].Pages).NotTo(ContainElement("https://www.google.com/jobs/"))
+	})
+
+	It("should write the clusters as text", func() {
+		clusters, _ := FindDuplicates(store, 1)
+
+		var out bytes.Buffer
+		WriteDuplicatesText(&out, clusters)
+		Expect(out.String()).To(ContainSubstring("Exact duplicates (1 clusters)"))
+		Expect(out.String()).To(ContainSubstring("Near duplicates (1 clusters)"))
+	})
+
+})
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"container/heap"
+	"net/url"
+)
+
+// The addresses waiting to be fetched. Abstracted so that very large sites
+// can keep their queue on disk instead of in memory
+type Frontier interface {
+	Push(entry FrontierEntry) error
+	// returns false when there is nothing left to pop
+	Pop() (FrontierEntry, bool, error)
+	Len() int
+}
+
+// An address waiting in the frontier along with its click depth from the root
+type FrontierEntry struct {
+	Address url.URL
+	Depth   int
+}
+
+// The addresses the mapper has already met (queued, fetched or in flight),
+// used to decide whether a link should be requested
+type SeenSet interface {
+	Add(address url.URL) error
+	Contains(address url.URL) (bool, error)
+}
+
+// Where the mapper stores the pages it retrieved along with their links
+type PageStore interface {
+	Put(page HtmlPageLinks) error
+	Get(address url.URL) (HtmlPageLinks, bool, error)
+	Len() int
+	// calls fn for every stored page, stops at the first error
+	Each(fn func(page HtmlPageLinks) error) error
+}
+
+// In memory Frontier, a heap sorted by the given Ordering
+type MemoryFrontier struct {
+	ordering Ordering
+	queue    frontierHeap
+	seq      uint64
+}
+
+func NewMemoryFrontier(ordering Ordering) *MemoryFrontier {
+	if ordering == nil {
+		ordering = FifoOrdering
+	}
+	return &MemoryFrontier{ordering: ordering, queue: make(frontierHeap, 0)}
+}
+
+func (frontier *MemoryFrontier) Push(entry FrontierEntry) error {
+	frontier.seq++
+	heap.Push(&frontier.queue, frontierItem{
+		frontier.ordering(entry, frontier.seq),
+		frontier.seq,
+		entry,
+	})
+	return nil
+}
+
+func (frontier *MemoryFrontier) Pop() (FrontierEntry, bool, error) {
+	if len(frontier.queue) == 0 {
+		return FrontierEntry{}, false, nil
+	}
+	item := heap.Pop(&frontier.queue).(frontierItem)
+	return item.entry, true, nil
+}
+
+func (frontier *MemoryFrontier) Len() int {
+	return len(frontier.queue)
+}
+
+type frontierItem struct {
+	key   int64
+	seq   uint64
+	entry FrontierEntry
+}
+
+// implements heap.Interface, lower keys first, ties broken by insertion order
+type frontierHeap []frontierItem
+
+func (h frontierHeap) Len() int { return len(h) }
+func (h frontierHeap) Less(i, j int) bool {
+	if h[i].key != h[j].key {
+		return h[i].key < h[j].key
+	}
+	return h[i].seq < h[j].seq
+}
+func (h frontierHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *frontierHeap) Push(x interface{}) { *h = append(*h, x.(frontierItem)) }
+func (h *frontierHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// In memory SeenSet, a plain map as the mapper always used
+type MemorySeenSet map[url.URL]bool
+
+func (set MemorySeenSet) Add(address url.URL) error {
+	set[address] = true
+	return nil
+}
+
+func (set MemorySeenSet) Contains(address url.URL) (bool, error) {
+	_, found := set[address]
+	return found, nil
+}
+
+// In memory PageStore
+type MemoryPageStore map[url.URL]HtmlPageLinks
+
+func (pages MemoryPageStore) Put(page HtmlPageLinks) error {
+	pages[page.Address] = page
+	return nil
+}
+
+func (pages MemoryPageStore) Get(address url.URL) (HtmlPageLinks, bool, error) {
+	page, found := pages[address]
+	return page, found, nil
+}
+
+func (pages MemoryPageStore) Len() int {
+	return len(pages)
+}
+
+func (pages MemoryPageStore) Each(fn func(page HtmlPageLinks) error) error {
+	for _, page := range pages {
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Loads the links held by a PageStore in memory (e.g. in order to print them),
+// pages requested for their status only are not part of the link graph
+func ToPagesMap(store PageStore) (PagesMap, error) {
+	pages := make(PagesMap, store.Len())
+	err := store.Each(func(page HtmlPageLinks) error {
+		if !page.Response.StatusOnly {
+			pages[page.Address] = page.LinksTo
+		}
+		return nil
+	})
+
+	return pages, err
+}
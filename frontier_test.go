@@ -0,0 +1,51 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/url"
+)
+
+var _ = Describe("Frontier", func() {
+
+	var (
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+	})
+
+	It("should pop addresses in the order they were pushed", func() {
+		frontier := NewMemoryFrontier(nil)
+
+		frontier.Push(FrontierEntry{*pageUrl, 0})
+		frontier.Push(FrontierEntry{*aboutPageUrl, 1})
+		Expect(frontier.Len()).To(Equal(2))
+
+		first, ok, _ := frontier.Pop()
+		Expect(ok).To(BeTrue())
+		Expect(first.Address).To(Equal(*pageUrl))
+
+		second, ok, _ := frontier.Pop()
+		Expect(ok).To(BeTrue())
+		Expect(second.Address).To(Equal(*aboutPageUrl))
+
+		_, ok, _ = frontier.Pop()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should load the links of any PageStore in memory", func() {
+		pages := make(MemoryPageStore)
+		pages.Put(HtmlPageLinks{Address: *pageUrl, LinksTo: []url.URL{*aboutPageUrl}})
+
+		res, err := ToPagesMap(pages)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(PagesMap{*pageUrl: {*aboutPageUrl}}))
+	})
+
+})
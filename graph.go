@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+)
+
+// What the link graph tells about a single page
+type PageLinkStats struct {
+	Address string `json:"address"`
+	// distinct crawled pages linking to this one (self links excluded)
+	Inbound int `json:"inbound"`
+	// distinct crawled pages this one links to (self links excluded)
+	Outbound int `json:"outbound"`
+	// clicks needed to reach the page from the root, -1 if it can't be reached
+	Depth    int     `json:"depth"`
+	PageRank float64 `json:"pagerank"`
+}
+
+// Link structure analysis of a crawl
+type LinkReport struct {
+	Root  string          `json:"root"`
+	Pages []PageLinkStats `json:"pages"`
+	// number of pages at each click depth, index is the depth
+	DepthHistogram []int `json:"depth_histogram"`
+	// reachable through a single internal link, one broken link away from being orphans
+	SingleInbound []string `json:"single_inbound"`
+	// no outbound internal links, the visitor can only go back
+	DeadEnds []string `json:"dead_ends"`
+	// deeper than the threshold given to AnalyzeLinks
+	DeepPages []string `json:"deep_pages"`
+	// crawled (e.g. from another seed) but not reachable from the root
+	Unreachable []string `json:"unreachable"`
+}
+
+// Counts each internal link once per page pair, links to pages that were not
+// crawled (other hosts) and self links are not internal links
+func internalLinks(pages PagesMap) map[url.URL]map[url.URL]bool {
+	links := make(map[url.URL]map[url.URL]bool, len(pages))
+	for address, linksTo := range pages {
+		targets := make(map[url.URL]bool)
+		for _, link := range linksTo {
+			if _, crawled := pages[link]; crawled && link != address {
+				targets[link] = true
+			}
+		}
+		links[address] = targets
+	}
+	return links
+}
+
+// Click depth of every page reachable from the root (breadth first visit)
+func ClickDepths(pages PagesMap, root url.URL) map[url.URL]int {
+	return clickDepths(internalLinks(pages), root)
+}
+
+func clickDepths(links map[url.URL]map[url.URL]bool, root url.URL) map[url.URL]int {
+	depths := map[url.URL]int{root: 0}
+	queue := []url.URL{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for next := range links[current] {
+			if _, visited := depths[next]; !visited {
+				depths[next] = depths[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return depths
+}
+
+// Analyses the link graph, pages deeper than deepThreshold clicks are reported as deep.
+// Broken pages (see CrawlResult.BrokenPages) have no links to follow, they are
+// left out of the single inbound, dead end and unreachable lists
+func AnalyzeLinks(pages PagesMap, broken map[url.URL]bool, root url.URL, deepThreshold int) LinkReport {
+	links := internalLinks(pages)
+	depths := clickDepths(links, root)
+	scores := PageRank(pages, DefaultPageRankOptions())
+
+	inbound := make(map[url.URL]int, len(pages))
+	for _, targets := range links {
+		for target := range targets {
+			inbound[target]++
+		}
+	}
+
+	report := LinkReport{
+		Root:           root.String(),
+		Pages:          make([]PageLinkStats, 0, len(pages)),
+		DepthHistogram: make([]int, 0),
+		SingleInbound:  make([]string, 0),
+		DeadEnds:       make([]string, 0),
+		DeepPages:      make([]string, 0),
+		Unreachable:    make([]string, 0),
+	}
+
+	for address := range pages {
+		depth, reachable := depths[address]
+		if !reachable {
+			depth = -1
+		}
+		stats := PageLinkStats{address.String(), inbound[address], len(links[address]), depth, scores[address]}
+		report.Pages = append(report.Pages, stats)
+
+		if stats.Inbound == 1 && !broken[address] {
+			report.SingleInbound = append(report.SingleInbound, stats.Address)
+		}
+		if stats.Outbound == 0 && !broken[address] {
+			report.DeadEnds = append(report.DeadEnds, stats.Address)
+		}
+		if !reachable {
+			if !broken[address] {
+				report.Unreachable = append(report.Unreachable, stats.Address)
+			}
+			continue
+		}
+		if depth > deepThreshold {
+			report.DeepPages = append(report.DeepPages, stats.Address)
+		}
+		for len(report.DepthHistogram) <= depth {
+			report.DepthHistogram = append(report.DepthHistogram, 0)
+		}
+		report.DepthHistogram[depth]++
+	}
+
+	// most linked pages first
+	sort.Slice(report.Pages, func(i, j int) bool {
+		if report.Pages[i].Inbound != report.Pages[j].Inbound {
+			return report.Pages[i].Inbound > report.Pages[j].Inbound
+		}
+		return report.Pages[i].Address < report.Pages[j].Address
+	})
+	sort.Strings(report.SingleInbound)
+	sort.Strings(report.DeadEnds)
+	sort.Strings(report.DeepPages)
+	sort.Strings(report.Unreachable)
+
+	return report
+}
+
+func (report LinkReport) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (report LinkReport) WriteText(w io.Writer) {
+	fmt.Fprintln(w, "Link report for", report.Root)
+	fmt.Fprintln(w, "Pages", len(report.Pages))
+
+	fmt.Fprintln(w, "Pages by click depth")
+	for depth, count := range report.DepthHistogram {
+		fmt.Fprintf(w, "  %d: %d\n", depth, count)
+	}
+
+	fmt.Fprintln(w, "Most linked pages")
+	for i, stats := range report.Pages {
+		if i == 10 {
+			break
+		}
+		fmt.Fprintf(w, "  %s %d inbound, %d outbound, depth %d\n", stats.Address, stats.Inbound, stats.Outbound, stats.Depth)
+	}
+
+	byRank := append([]PageLinkStats{}, report.Pages...)
+	sort.SliceStable(byRank, func(i, j int) bool {
+		return byRank[i].PageRank > byRank[j].PageRank
+	})
+	fmt.Fprintln(w, "Highest PageRank")
+	for i, stats := range byRank {
+		if i == 10 {
+			break
+		}
+		fmt.Fprintf(w, "  %s %.4f (%d inbound)\n", stats.Address, stats.PageRank, stats.Inbound)
+	}
+
+	sections := []struct {
+		title     string
+		addresses []string
+	}{
+		{"Pages with a single inbound link", report.SingleInbound},
+		{"Dead ends", report.DeadEnds},
+		{"Deep pages", report.DeepPages},
+		{"Not reachable from the root", report.Unreachable},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, "%s (%d)\n", section.title, len(section.addresses))
+		for _, address := range section.addresses {
+			fmt.Fprintln(w, "  ", address)
+		}
+	}
+}
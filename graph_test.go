@@ -0,0 +1,79 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/url"
+)
+
+var _ = Describe("AnalyzeLinks", func() {
+
+	var (
+		pageUrl *url.URL
+		monzoUrl *url.URL
+		aboutPageUrl *url.URL
+		otherPageUrl *url.URL
+		lastPageUrl *url.URL
+		islandUrl *url.URL
+		pages PagesMap
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		monzoUrl, _ = url.Parse("https://www.monzo.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+		otherPageUrl, _ = url.Parse("https://www.google.com/other")
+		lastPageUrl, _ = url.Parse("https://www.google.com/42")
+		islandUrl, _ = url.Parse("https://www.google.com/island")
+
+		pages = PagesMap{
+			*pageUrl: {*aboutPageUrl, *otherPageUrl, *monzoUrl},
+			*aboutPageUrl: {*pageUrl, *aboutPageUrl},
+			*otherPageUrl: {*pageUrl, *lastPageUrl},
+			*lastPageUrl: {},
+			*islandUrl: {*pageUrl},
+		}
+	})
+
+	It("should compute the click depth from the root", func() {
+		Expect(ClickDepths(pages, *pageUrl)).To(Equal(map[url.URL]int{
+			*pageUrl: 0,
+			*aboutPageUrl: 1,
+			*otherPageUrl: 1,
+			*lastPageUrl: 2,
+		}))
+	})
+
+	It("should report the link structure", func() {
+		report := AnalyzeLinks(pages, nil, *pageUrl, 1)
+
+		Expect(report.Pages[0].Address).To(Equal(pageUrl.String()))
+		Expect(report.Pages[0].Inbound).To(Equal(3))
+		Expect(report.Pages[0].Outbound).To(Equal(2))
+		Expect(report.Pages[0].Depth).To(Equal(0))
+		Expect(report.Pages[0].PageRank).To(BeNumerically(">", 0))
+		Expect(report.DepthHistogram).To(Equal([]int{1, 2, 1}))
+		Expect(report.SingleInbound).To(Equal([]string{
+			lastPageUrl.String(), aboutPageUrl.String(), otherPageUrl.String(),
+		}))
+		Expect(report.DeadEnds).To(Equal([]string{lastPageUrl.String()}))
+		Expect(report.DeepPages).To(Equal([]string{lastPageUrl.String()}))
+		Expect(report.Unreachable).To(Equal([]string{islandUrl.String()}))
+	})
+
+	It("should not report broken pages as dead ends, single inbound or unreachable", func() {
+		brokenUrl, _ := url.Parse("https://www.google.com/broken")
+		pages[*aboutPageUrl] = append(pages[*aboutPageUrl], *brokenUrl)
+		pages[*brokenUrl] = []url.URL{}
+		pages[*islandUrl] = []url.URL{}
+
+		report := AnalyzeLinks(pages, map[url.URL]bool{*brokenUrl: true, *islandUrl: true}, *pageUrl, 1)
+
+		Expect(report.Pages).To(HaveLen(6))
+		Expect(report.DeadEnds).To(Equal([]string{lastPageUrl.String()}))
+		Expect(report.SingleInbound).NotTo(ContainElement(brokenUrl.String()))
+		Expect(report.Unreachable).To(BeEmpty())
+	})
+
+})
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// language, optional script and optional region (e.g. en, zh-Hant, es-419, en-GB)
+var hreflangPattern = regexp.MustCompile(`(?i)^[a-z]{2,3}(-[a-z]{4})?(-([a-z]{2}|[0-9]{3}))?$`)
+
+// Checks an hreflang value, returns an explanation when it is not valid
+func checkHreflang(lang string) (string, bool) {
+	if strings.EqualFold(lang, "x-default") {
+		return "", true
+	}
+	if !hreflangPattern.MatchString(lang) {
+		return fmt.Sprintf("%q is not a language code", lang), false
+	}
+	// the most common mistake, UK is not an ISO 3166 code
+	if parts := strings.Split(lang, "-"); len(parts) > 1 && strings.EqualFold(parts[len(parts)-1], "uk") {
+		return fmt.Sprintf("%q, the region of the United Kingdom is GB", lang), false
+	}
+	return "", true
+}
+
+func IsValidHreflang(lang string) bool {
+	_, valid := checkHreflang(lang)
+	return valid
+}
+
+// Validates the canonical and the hreflang alternates of the pages reached
+// following links against what the crawl found at the addresses they point to.
+// Targets that were not crawled (e.g. on other hosts) can't be checked and are
+// skipped
+func relationIssues(crawled map[url.URL]HtmlPageLinks) []AuditIssue {
+	issues := make([]AuditIssue, 0)
+	issue := func(severity string, check string, address url.URL, detail string) {
+		issues = append(issues, AuditIssue{severity, check, address.String(), detail})
+	}
+	target := func(raw string) (HtmlPageLinks, bool) {
+		address, err := url.Parse(raw)
+		if err != nil {
+			return HtmlPageLinks{}, false
+		}
+		page, found := crawled[*address]
+		return page, found
+	}
+
+	for _, page := range crawled {
+		if isBrokenStatus(page.Response.StatusCode) || page.Response.StatusOnly {
+			continue
+		}
+		info := page.Info
+
+		if canonical, found := target(info.Canonical); found && info.Canonical != page.Address.String() {
+			if isBrokenStatus(canonical.Response.StatusCode) {
+				issue(SeverityHigh, "canonical-broken", page.Address,
+					fmt.Sprintf("canonical %s answered %d", info.Canonical, canonical.Response.StatusCode))
+			} else if canonical.Response.RedirectedTo != "" {
+				issue(SeverityMedium, "canonical-redirect", page.Address,
+					fmt.Sprintf("canonical %s redirects to %s", info.Canonical, canonical.Response.RedirectedTo))
+			}
+		}
+
+		if len(info.Alternates) == 0 {
+			continue
+		}
+
+		hasDefault := false
+		for _, alternate := range info.Alternates {
+			if strings.EqualFold(alternate.Lang, "x-default") {
+				hasDefault = true
+			}
+			if reason, valid := checkHreflang(alternate.Lang); !valid {
+				issue(SeverityMedium, "hreflang-invalid-lang", page.Address, reason)
+			}
+
+			other, found := target(alternate.Href)
+			if !found || alternate.Href == page.Address.String() {
+				continue
+			}
+			if isBrokenStatus(other.Response.StatusCode) {
+				issue(SeverityHigh, "hreflang-broken", page.Address,
+					fmt.Sprintf("%s alternate %s answered %d", alternate.Lang, alternate.Href, other.Response.StatusCode))
+			} else if !linksBack(other.Info, page.Address) {
+				issue(SeverityMedium, "hreflang-not-reciprocal", page.Address,
+					fmt.Sprintf("%s alternate %s does not link back", alternate.Lang, alternate.Href))
+			}
+		}
+
+		if !hasDefault {
+			issue(SeverityLow, "hreflang-missing-x-default", page.Address,
+				fmt.Sprintf("%d alternates, none is x-default", len(info.Alternates)))
+		}
+	}
+
+	return issues
+}
+
+// true if the page lists the address among its alternates
+func linksBack(info PageInfo, address url.URL) bool {
+	for _, alternate := range info.Alternates {
+		if alternate.Href == address.String() {
+			return true
+		}
+	}
+	return false
+}
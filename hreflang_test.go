@@ -0,0 +1,104 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/url"
+)
+
+var _ = Describe("IsValidHreflang", func() {
+
+	It("should accept languages with optional script and region", func() {
+		for _, lang := range []string{"en", "en-GB", "zh-Hant", "zh-Hant-TW", "es-419", "X-Default"} {
+			Expect(IsValidHreflang(lang)).To(BeTrue(), lang)
+		}
+	})
+
+	It("should reject malformed codes and the UK region", func() {
+		for _, lang := range []string{"", "english", "en_GB", "en-", "en-UK"} {
+			Expect(IsValidHreflang(lang)).To(BeFalse(), lang)
+		}
+	})
+
+})
+
+var _ = Describe("AuditCrawl relations", func() {
+
+	var (
+		enUrl *url.URL
+		itUrl *url.URL
+		frUrl *url.URL
+		movedUrl *url.URL
+		goneUrl *url.URL
+		store MemoryPageStore
+		options AuditOptions
+	)
+
+	BeforeEach(func() {
+		enUrl, _ = url.Parse("https://www.google.com/en/")
+		itUrl, _ = url.Parse("https://www.google.com/it/")
+		frUrl, _ = url.Parse("https://www.google.com/fr/")
+		movedUrl, _ = url.Parse("https://www.google.com/moved")
+		goneUrl, _ = url.Parse("https://www.google.com/gone")
+
+		// only the relation checks can fail
+		options = AuditOptions{MaxUrlLength: 100, MinWordCount: 0}
+		ok := ResponseInfo{StatusCode: 200}
+		store = make(MemoryPageStore)
+
+		store.Put(HtmlPageLinks{
+			Address: *enUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "en", Description: "en", Alternates: []Alternate{
+				{Lang: "en", Href: enUrl.String()},
+				{Lang: "it", Href: itUrl.String()},
+				{Lang: "fr", Href: frUrl.String()},
+				{Lang: "x-default", Href: enUrl.String()},
+			}},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *itUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "it", Description: "it", Canonical: movedUrl.String(), Alternates: []Alternate{
+				{Lang: "en-UK", Href: enUrl.String()},
+				{Lang: "it", Href: itUrl.String()},
+			}},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *frUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "fr", Description: "fr", Canonical: goneUrl.String()},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *movedUrl,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 200, RedirectedTo: itUrl.String()},
+			Info: PageInfo{Title: "moved", Description: "moved"},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *goneUrl,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 404},
+		})
+	})
+
+	It("should validate canonicals and hreflang alternates", func() {
+		report, err := AuditCrawl(*enUrl, store, options)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Issues).To(Equal([]AuditIssue{
+			{SeverityHigh, "canonical-broken", frUrl.String(), "canonical " + goneUrl.String() + " answered 404"},
+			{SeverityMedium, "canonical-elsewhere", frUrl.String(), "canonical is " + goneUrl.String()},
+			{SeverityMedium, "canonical-elsewhere", itUrl.String(), "canonical is " + movedUrl.String()},
+			{SeverityMedium, "canonical-redirect", itUrl.String(), "canonical " + movedUrl.String() + " redirects to " + itUrl.String()},
+			{SeverityMedium, "hreflang-invalid-lang", itUrl.String(), `"en-UK", the region of the United Kingdom is GB`},
+			{SeverityMedium, "hreflang-not-reciprocal", enUrl.String(), "fr alternate " + frUrl.String() + " does not link back"},
+			{SeverityLow, "hreflang-missing-x-default", itUrl.String(), "2 alternates, none is x-default"},
+		}))
+	})
+
+})
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Returned in offline mode for the addresses missing from the cache
+var ErrNotCached = errors.New("address not in cache")
+
+// Names of the files written by the cache: entries (see cacheKey) and the
+// temporary files of the entries being stored
+var cacheEntryName = regexp.MustCompile("^[0-9a-f]{64}$")
+
+func isCacheFile(name string) bool {
+	return cacheEntryName.MatchString(name) || strings.HasPrefix(name, "tmp-")
+}
+
+// Stored in the dump, the address of the last request when redirected
+const cachedUrlHeader = "X-Sitemapper-Url"
+
+// HttpClient keeping a copy of every response on disk, meant to avoid
+// downloading the same site over and over while developing. Responses are
+// stored as raw http dumps, one file per address. Cache misses are requested
+// with the headers and the context given (conditional or traced requests)
+// when the wrapped client supports them
+type CachingHttpClient struct {
+	client HttpClient
+	dir    string
+	// entries older than this are fetched again, 0 means they never expire
+	ttl time.Duration
+	// never hit the network, addresses missing from the cache fail with ErrNotCached
+	offline bool
+}
+
+func NewCachingHttpClient(client HttpClient, dir string, ttl time.Duration, offline bool) (*CachingHttpClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &CachingHttpClient{client, dir, ttl, offline}, nil
+}
+
+func cacheKey(address url.URL) string {
+	sum := sha256.Sum256([]byte(address.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (cache *CachingHttpClient) path(address url.URL) string {
+	return filepath.Join(cache.dir, cacheKey(address))
+}
+
+func (cache *CachingHttpClient) Get(address url.URL) (resp *http.Response, err error) {
+	return cache.GetWithContext(context.Background(), address, nil)
+}
+
+func (cache *CachingHttpClient) GetWithHeader(address url.URL, header http.Header) (resp *http.Response, err error) {
+	return cache.GetWithContext(context.Background(), address, header)
+}
+
+func (cache *CachingHttpClient) GetWithContext(ctx context.Context, address url.URL, header http.Header) (resp *http.Response, err error) {
+	resp, err = cache.load(address)
+	if err == nil {
+		log.Debug("Cache hit ", address)
+		return resp, nil
+	}
+	if !os.IsNotExist(err) {
+		log.Warn("Can't read cache entry for ", address, " ", err)
+	}
+
+	if cache.offline {
+		return nil, ErrNotCached
+	}
+
+	resp, err = cache.fetch(ctx, address, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified || resp.StatusCode >= 500 {
+		// no body to keep (the caller already has the page) or a server
+		// error that may well be gone next time
+		return resp, nil
+	}
+
+	// the client follows redirects, the request of the response is the last one
+	if resp.Request != nil && resp.Request.URL != nil && resp.Request.URL.String() != address.String() {
+		resp.Header.Set(cachedUrlHeader, resp.Request.URL.String())
+	}
+
+	// the body can only be read once, keep a copy for the caller
+	dump, err := httputil.DumpResponse(resp, true)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.store(address, dump); err != nil {
+		log.Warn("Can't write cache entry for ", address, " ", err)
+	}
+
+	return readDump(address, dump)
+}
+
+// requests the address with the most capable interface of the wrapped client
+func (cache *CachingHttpClient) fetch(ctx context.Context, address url.URL, header http.Header) (*http.Response, error) {
+	switch client := cache.client.(type) {
+	case ContextHttpClient:
+		return client.GetWithContext(ctx, address, header)
+	case HeaderHttpClient:
+		return client.GetWithHeader(address, header)
+	default:
+		return client.Get(address)
+	}
+}
+
+// the response of the dump stored for the address, along with the request
+// it answered (the last one when redirected)
+func readDump(address url.URL, dump []byte) (*http.Response, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), nil)
+	if err != nil {
+		return nil, err
+	}
+	final := &address
+	if stored := resp.Header.Get(cachedUrlHeader); stored != "" {
+		if redirected, err := url.Parse(stored); err == nil {
+			final = redirected
+		}
+		resp.Header.Del(cachedUrlHeader)
+	}
+	resp.Request = &http.Request{Method: http.MethodGet, URL: final}
+	return resp, nil
+}
+
+// returns an error satisfying os.IsNotExist for missing and expired entries
+// (in offline mode expired entries are better than nothing, they are served anyway)
+func (cache *CachingHttpClient) load(address url.URL) (*http.Response, error) {
+	path := cache.path(address)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if cache.ttl > 0 && !cache.offline && time.Since(info.ModTime()) > cache.ttl {
+		return nil, os.ErrNotExist
+	}
+	dump, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return readDump(address, dump)
+}
+
+// several fetchers may store at the same time, writing to a temporary file
+// and renaming it keeps readers from seeing partial entries
+func (cache *CachingHttpClient) store(address url.URL, dump []byte) error {
+	tmp, err := ioutil.TempFile(cache.dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(dump); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), cache.path(address))
+}
+
+// Removes the entries older than ttl (all of them if ttl is 0), returns how many
+// were removed. Files not written by the cache are left alone
+func ClearHttpCache(dir string, ttl time.Duration) (int, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !isCacheFile(entry.Name()) || (ttl > 0 && time.Since(entry.ModTime()) <= ttl) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,178 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+)
+
+// Counts the requests that reached the "network"
+type CountingHttpClientMock struct {
+	calls int
+}
+
+func (client *CountingHttpClientMock) Get (address url.URL) (resp *http.Response, err error) {
+	client.calls++
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("ETag", `"v1"`)
+	recorder.WriteString("<html>" + address.Path + "</html>")
+	return recorder.Result(), nil
+}
+
+// Answers every request with the given status
+type StatusHttpClientMock struct {
+	status int
+	calls int
+}
+
+func (client *StatusHttpClientMock) Get (address url.URL) (resp *http.Response, err error) {
+	client.calls++
+	recorder := httptest.NewRecorder()
+	recorder.WriteHeader(client.status)
+	return recorder.Result(), nil
+}
+
+var _ = Describe("CachingHttpClient", func() {
+
+	var (
+		dir string
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+	)
+
+	readBody := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+
+		var err error
+		dir, err = ioutil.TempDir("", "sitemapper")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should serve the second request from the cache", func() {
+		network := CountingHttpClientMock{}
+		client, err := NewCachingHttpClient(&network, dir, 0, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		first, err := client.Get(*pageUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readBody(first)).To(Equal("<html>/</html>"))
+
+		second, err := client.Get(*pageUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readBody(second)).To(Equal("<html>/</html>"))
+		Expect(second.StatusCode).To(Equal(200))
+		Expect(second.Header.Get("ETag")).To(Equal(`"v1"`))
+
+		Expect(network.calls).To(Equal(1))
+	})
+
+	It("should not hit the network when offline", func() {
+		network := CountingHttpClientMock{}
+		online, _ := NewCachingHttpClient(&network, dir, 0, false)
+		online.Get(*pageUrl)
+
+		offline, _ := NewCachingHttpClient(&network, dir, 0, true)
+
+		cached, err := offline.Get(*pageUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readBody(cached)).To(Equal("<html>/</html>"))
+
+		_, err = offline.Get(*aboutPageUrl)
+		Expect(err).To(Equal(ErrNotCached))
+
+		Expect(network.calls).To(Equal(1))
+	})
+
+	It("should remember where the address redirected to", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/old" {
+				http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+				return
+			}
+			w.Write([]byte("<html>new</html>"))
+		}))
+		defer server.Close()
+		old, _ := url.Parse(server.URL + "/old")
+
+		client, _ := NewCachingHttpClient(&DefaultHttpClient{}, dir, 0, false)
+		first, err := client.Get(*old)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.Request.URL.String()).To(Equal(server.URL + "/new"))
+
+		server.Close()
+		cached, err := client.Get(*old)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readBody(cached)).To(Equal("<html>new</html>"))
+		Expect(cached.Request.URL.String()).To(Equal(server.URL + "/new"))
+		Expect(cached.Header.Get("X-Sitemapper-Url")).To(BeEmpty())
+	})
+
+	It("should send the conditional headers of cache misses", func() {
+		network := ConditionalHttpClientMock{`"v1"`, make(chan http.Header, 2)}
+		client, _ := NewCachingHttpClient(&network, dir, 0, false)
+
+		conditional := make(http.Header)
+		conditional.Set("If-None-Match", `"v1"`)
+		resp, err := client.GetWithHeader(*pageUrl, conditional)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotModified))
+		Expect((<-network.headers).Get("If-None-Match")).To(Equal(`"v1"`))
+
+		// the 304 is not cached, the page is requested again
+		resp, err = client.Get(*pageUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readBody(resp)).To(Equal("<html>changed</html>"))
+		Expect(network.headers).To(Receive())
+	})
+
+	It("should not keep server errors", func() {
+		failing := &StatusHttpClientMock{status: http.StatusServiceUnavailable}
+		client, _ := NewCachingHttpClient(failing, dir, 0, false)
+
+		resp, err := client.Get(*pageUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+		failing.status = http.StatusOK
+		resp, err = client.Get(*pageUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(failing.calls).To(Equal(2))
+	})
+
+	It("should forget everything once cleared", func() {
+		network := CountingHttpClientMock{}
+		client, _ := NewCachingHttpClient(&network, dir, 0, false)
+		client.Get(*pageUrl)
+		client.Get(*aboutPageUrl)
+
+		other := dir + "/notes.txt"
+		Expect(ioutil.WriteFile(other, []byte("keep me"), 0644)).To(Succeed())
+
+		removed, err := ClearHttpCache(dir, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(Equal(2))
+		Expect(other).To(BeAnExistingFile())
+
+		client.Get(*pageUrl)
+		Expect(network.calls).To(Equal(3))
+	})
+
+})
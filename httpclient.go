@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tunables for NewDefaultHttpClient, the zero value behaves like http.Get
+type HttpClientOptions struct {
+	UserAgent string
+	// sent with every request (e.g. the token opening a staging environment)
+	Header http.Header
+	// keep the cookies set by the sites, as a browser would
+	Cookies bool
+	// Netscape cookies.txt file (as exported by browsers and curl) whose
+	// cookies are sent from the first request, implies Cookies
+	CookiesFile string
+	// each sent only to its own host, never to the others
+	Credentials []HostCredentials
+	// http://, https:// or socks5:// proxy, by default the one of the
+	// HTTP_PROXY and HTTPS_PROXY environment variables
+	Proxy string
+	// PEM bundle of certificate authorities trusted along with the system ones
+	CaFile string
+	// PEM client certificate and key, for servers requiring mutual TLS
+	CertFile string
+	KeyFile  string
+	// accept any server certificate, for tests against self signed servers only
+	InsecureSkipVerify bool
+}
+
+func NewDefaultHttpClient(options HttpClientOptions) (*DefaultHttpClient, error) {
+	client := &DefaultHttpClient{UserAgent: options.UserAgent, Header: options.Header}
+
+	if options.Cookies || options.CookiesFile != "" {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		if options.CookiesFile != "" {
+			file, err := os.Open(options.CookiesFile)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			if _, err := LoadCookiesTxt(jar, file); err != nil {
+				return nil, fmt.Errorf("%s: %v", options.CookiesFile, err)
+			}
+		}
+		client.Client = &http.Client{Jar: jar}
+	}
+
+	transport, err := newTransport(options)
+	if err != nil {
+		return nil, err
+	}
+	if len(options.Credentials) > 0 {
+		transport = &authTransport{transport, options.Credentials}
+	}
+	if transport != http.DefaultTransport {
+		if client.Client == nil {
+			client.Client = &http.Client{}
+		}
+		client.Client.Transport = transport
+	}
+
+	return client, nil
+}
+
+// Parses "Name: value" headers as given on the command line
+func ParseHeaders(lines []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, line := range lines {
+		parts := strings.SplitN(line, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("header %q is not in the Name: value form", line)
+		}
+		header.Add(textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(parts[1]))
+	}
+	return header, nil
+}
+
+// Adds to the jar the cookies listed in the Netscape cookies.txt format, one
+// per line: domain, include subdomains, path, secure, expiration (unix time, 0
+// for session cookies), name and value separated by tabs. Returns the number
+// of cookies read
+func LoadCookiesTxt(jar http.CookieJar, r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	count := 0
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			// curl marks the http only cookies this way, not a comment
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return count, fmt.Errorf("line %d: expected 7 tab separated fields, found %d", number, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return count, fmt.Errorf("line %d: invalid expiration %q", number, fields[4])
+		}
+
+		domain := fields[0]
+		secure := strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(fields[1], "TRUE") {
+			// without a domain the jar keeps the cookie for the exact host only
+			cookie.Domain = domain
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: fields[2]}, []*http.Cookie{cookie})
+		count++
+	}
+	return count, scanner.Err()
+}
@@ -0,0 +1,144 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var _ = Describe("DefaultHttpClient", func() {
+
+	var (
+		server *httptest.Server
+		requests chan *http.Request
+		serverUrl *url.URL
+	)
+
+	BeforeEach(func() {
+		requests = make(chan *http.Request, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests <- r
+			if r.URL.Path == "/login" {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "42", Path: "/"})
+			}
+		}))
+		serverUrl, _ = url.Parse(server.URL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should send the user agent and the configured headers", func() {
+		client, err := NewDefaultHttpClient(HttpClientOptions{
+			UserAgent: "sitemapper-test",
+			Header: http.Header{"X-Staging-Token": {"secret"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := client.GetWithHeader(*serverUrl, http.Header{"If-None-Match": {`"v1"`}})
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+
+		request := <-requests
+		Expect(request.UserAgent()).To(Equal("sitemapper-test"))
+		Expect(request.Header.Get("X-Staging-Token")).To(Equal("secret"))
+		Expect(request.Header.Get("If-None-Match")).To(Equal(`"v1"`))
+	})
+
+	It("should send back the cookies set by the server", func() {
+		client, err := NewDefaultHttpClient(HttpClientOptions{Cookies: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		login := serverUrl.ResolveReference(&url.URL{Path: "/login"})
+		resp, _ := client.Get(*login)
+		resp.Body.Close()
+		resp, _ = client.Get(*serverUrl)
+		resp.Body.Close()
+
+		Expect((<-requests).Cookies()).To(BeEmpty())
+		cookie, err := (<-requests).Cookie("session")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cookie.Value).To(Equal("42"))
+	})
+
+	It("should send the cookies of a cookies.txt file", func() {
+		dir, err := ioutil.TempDir("", "sitemapper")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "cookies.txt")
+		content := "# Netscape HTTP Cookie File\n" + serverUrl.Hostname() + "\tFALSE\t/\tFALSE\t0\tconsent\tyes\n"
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+
+		client, err := NewDefaultHttpClient(HttpClientOptions{CookiesFile: path})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, _ := client.Get(*serverUrl)
+		resp.Body.Close()
+
+		cookie, err := (<-requests).Cookie("consent")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cookie.Value).To(Equal("yes"))
+	})
+
+})
+
+var _ = Describe("LoadCookiesTxt", func() {
+
+	It("should load domain, host only, secure and http only cookies", func() {
+		jar, _ := cookiejar.New(nil)
+		count, err := LoadCookiesTxt(jar, strings.NewReader(
+			"# Netscape HTTP Cookie File\n" +
+			"\n" +
+			".google.com\tTRUE\t/\tFALSE\t0\tshared\t1\n" +
+			"www.google.com\tFALSE\t/\tTRUE\t0\tsecure\t2\n" +
+			"#HttpOnly_www.google.com\tFALSE\t/about\tFALSE\t0\tabout\t3\n" +
+			"www.google.com\tFALSE\t/\tFALSE\t1\texpired\t4\n"))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(4))
+
+		names := func(raw string) []string {
+			address, _ := url.Parse(raw)
+			res := make([]string, 0)
+			for _, cookie := range jar.Cookies(address) {
+				res = append(res, cookie.Name)
+			}
+			return res
+		}
+		Expect(names("https://www.google.com/about")).To(ConsistOf("shared", "secure", "about"))
+		Expect(names("http://www.google.com/")).To(ConsistOf("shared"))
+		Expect(names("https://mail.google.com/")).To(ConsistOf("shared"))
+	})
+
+	It("should fail on malformed lines", func() {
+		jar, _ := cookiejar.New(nil)
+		_, err := LoadCookiesTxt(jar, strings.NewReader("www.google.com\tFALSE\t/\n"))
+
+		Expect(err).To(MatchError(ContainSubstring("line 1")))
+	})
+
+})
+
+var _ = Describe("ParseHeaders", func() {
+
+	It("should parse Name: value headers", func() {
+		header, err := ParseHeaders([]string{"x-token: a: b", "Accept-Language:it"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(header).To(Equal(http.Header{"X-Token": {"a: b"}, "Accept-Language": {"it"}}))
+
+		_, err = ParseHeaders([]string{"no colon"})
+		Expect(err).To(HaveOccurred())
+	})
+
+})
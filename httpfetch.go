@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/url"
 	"io/ioutil"
 	"sync"
+	"time"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -12,6 +15,31 @@ import (
 type HtmlPage struct {
 	Address url.URL
 	Bytes []byte
+	Response ResponseInfo
+	// set when the page did not change since the previous crawl (Response.NotModified),
+	// what was extracted back then is reused instead of parsing Bytes
+	Previous *HtmlPageLinks
+	// the request failed (the error was reported already), Bytes and Response
+	// hold whatever was received, there is nothing to parse
+	Failed bool
+}
+
+// What we keep of the http response besides the body
+type ResponseInfo struct {
+	// 0 when the page could not be fetched at all
+	StatusCode int
+	ETag string
+	LastModified string
+	// the server answered 304 to our conditional request, StatusCode is
+	// the one recorded by the previous crawl
+	NotModified bool
+	// where the redirects led, empty if the page answered directly
+	RedirectedTo string
+	// nil unless FetcherOptions.Timings is set
+	Timing *RequestTiming
+	// requested for its status only (canonical or alternate of a crawled page,
+	// not linked), set by the mapper: its links are not part of the crawl
+	StatusOnly bool
 }
 
 // Abstracting access to network in order to mock it during tests
@@ -19,11 +47,98 @@ type HttpClient interface {
 	Get (address url.URL) (resp *http.Response, err error)
 }
 
-// Default implementation of HttpClient uses the DefaultClient of the http package
-type DefaultHttpClient struct {}
+// Implemented by clients able to add headers to their requests, needed to
+// send conditional requests
+type HeaderHttpClient interface {
+	HttpClient
+	GetWithHeader (address url.URL, header http.Header) (resp *http.Response, err error)
+}
+
+// Implemented by clients able to run requests within a context, needed to
+// trace them (see RequestTiming)
+type ContextHttpClient interface {
+	HeaderHttpClient
+	GetWithContext (ctx context.Context, address url.URL, header http.Header) (resp *http.Response, err error)
+}
+
+// Default implementation of HttpClient, the zero value uses the DefaultClient of
+// the http package. See NewDefaultHttpClient to configure it
+type DefaultHttpClient struct {
+	// http.DefaultClient if nil
+	Client *http.Client
+	// sent instead of Go's default one when not empty
+	UserAgent string
+	// sent with every request
+	Header http.Header
+}
 
 func (client *DefaultHttpClient) Get (address url.URL) (resp *http.Response, err error) {
-	return http.Get(address.String())
+	return client.GetWithHeader(address, nil)
+}
+
+func (client *DefaultHttpClient) GetWithHeader (address url.URL, header http.Header) (resp *http.Response, err error) {
+	return client.GetWithContext(context.Background(), address, header)
+}
+
+func (client *DefaultHttpClient) GetWithContext (ctx context.Context, address url.URL, header http.Header) (resp *http.Response, err error) {
+	req, err := client.newRequest("GET", address, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	return client.httpClient().Do(req.WithContext(ctx))
+}
+
+// a request carrying the configured headers and user agent plus the given headers
+func (client *DefaultHttpClient) newRequest (method string, address url.URL, body io.Reader, header http.Header) (*http.Request, error) {
+	req, err := http.NewRequest(method, address.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range client.Header {
+		req.Header[name] = append([]string{}, values...)
+	}
+	for name, values := range header {
+		req.Header[name] = append([]string{}, values...)
+	}
+	if client.UserAgent != "" {
+		req.Header.Set("User-Agent", client.UserAgent)
+	}
+	return req, nil
+}
+
+func (client *DefaultHttpClient) httpClient () *http.Client {
+	if client.Client == nil {
+		return http.DefaultClient
+	}
+	return client.Client
+}
+
+// Tunables for StartHttpFetchersWithOptions
+type FetcherOptions struct {
+	// pages retrieved by a previous crawl, when they carry an ETag or a Last-Modified
+	// they are requested conditionally and reused if the server answers 304
+	Previous PageStore
+	// measure each request, see RequestTiming
+	Timings bool
+	Metrics *Metrics
+	// notified of each page fetched or failed
+	Hooks *CrawlHooks
+}
+
+// Builds the conditional headers for the page as it was seen by a previous crawl,
+// returns nil if there is nothing to validate against
+func conditionalHeader(previous HtmlPageLinks) http.Header {
+	if previous.Response.ETag == "" && previous.Response.LastModified == "" {
+		return nil
+	}
+	header := make(http.Header)
+	if previous.Response.ETag != "" {
+		header.Set("If-None-Match", previous.Response.ETag)
+	}
+	if previous.Response.LastModified != "" {
+		header.Set("If-Modified-Since", previous.Response.LastModified)
+	}
+	return header
 }
 
 // This function uses the given client to fetch the page at the given address and
@@ -33,38 +148,117 @@ func httpFetch(
 	address url.URL,
 	output chan HtmlPage,
 	wg *sync.WaitGroup,
+	options FetcherOptions,
 ) {
 	defer wg.Done()
 
 	log.Debug("Hitting network for ", address)
 
+	var previous HtmlPageLinks
+	var header http.Header
+	if options.Previous != nil {
+		found := false
+		var err error
+		previous, found, err = options.Previous.Get(address)
+		if err != nil {
+			log.Warn("Can't read previous crawl for ", address, err)
+		}
+		if found && previous.Response.StatusOnly {
+			// its links were not extracted back then, there is nothing to reuse
+			previous = HtmlPageLinks{}
+		} else if found {
+			header = conditionalHeader(previous)
+		}
+	}
+	headerClient, canSendHeader := client.(HeaderHttpClient)
+	contextClient, canTrace := client.(ContextHttpClient)
+
 	// isolated in order to unify calls to the chan and to eventually
 	// implement retries
-	fetch := func() ([]byte, error) {
-		resp, err := client.Get(address)
+	fetch := func() ([]byte, ResponseInfo, error) {
+		var resp *http.Response
+		var err error
+		var tracer *requestTracer
+		if options.Timings {
+			tracer = newRequestTracer()
+		}
+		switch {
+		case tracer != nil && canTrace:
+			resp, err = contextClient.GetWithContext(tracer.context(context.Background()), address, header)
+		case header != nil && canSendHeader:
+			resp, err = headerClient.GetWithHeader(address, header)
+		default:
+			resp, err = client.Get(address)
+		}
 		if err != nil {
-			return make([]byte, 0), err
+			return make([]byte, 0), ResponseInfo{}, err
 		}
 		// close the response once we've read and published it
 		defer resp.Body.Close()
 
+		info := ResponseInfo{
+			StatusCode: resp.StatusCode,
+			ETag: resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+		// the client follows redirects, the request of the response is the last one
+		if resp.Request != nil && resp.Request.URL != nil && resp.Request.URL.String() != address.String() {
+			info.RedirectedTo = resp.Request.URL.String()
+		}
+
+		if resp.StatusCode == http.StatusNotModified {
+			// the validators may be omitted from the 304, keep the old ones in that case
+			info.StatusCode = previous.Response.StatusCode
+			info.NotModified = true
+			if info.ETag == "" {
+				info.ETag = previous.Response.ETag
+			}
+			if info.LastModified == "" {
+				info.LastModified = previous.Response.LastModified
+			}
+			if tracer != nil {
+				info.Timing = tracer.finish(0)
+			}
+			return make([]byte, 0), info, nil
+		}
+
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return make([]byte, 0), err
+			return make([]byte, 0), info, err
+		}
+		if tracer != nil {
+			info.Timing = tracer.finish(len(body))
 		}
 
-		return body, nil
+		return body, info, nil
 	}
 
-	html, err := fetch()
+	start := time.Now()
+	options.Metrics.AddStage(StageFetching, 1)
+	html, info, err := fetch()
+	options.Metrics.AddStage(StageFetching, -1)
+	// failed pages go through the extractor as well
+	options.Metrics.AddStage(StageAwaitingParse, 1)
 	if err != nil {
 		// currently in case of error we just skip the page
 		// TODO wait & retry
 		log.Error("Could not read ", address.String(), err)
+		options.Metrics.CountError(ErrorFetch)
+		options.Hooks.Failed(address, err)
+		output <- HtmlPage{Address: address, Bytes: html, Response: info, Failed: true}
+		return
 	}
+	options.Metrics.ObserveFetch(info.StatusCode, len(html), time.Since(start))
 
-	log.Debug("Page retrieved ", address)
-	output <- HtmlPage{ address, html }
+	page := HtmlPage{Address: address, Bytes: html, Response: info}
+	if info.NotModified {
+		log.Debug("Page not modified ", address)
+		page.Previous = &previous
+	} else {
+		log.Debug("Page retrieved ", address)
+	}
+	options.Hooks.Fetched(page)
+	output <- page
 }
 
 /**
@@ -76,6 +270,15 @@ func StartHttpFetchers(
 	urlChannel chan url.URL,
 	httpClient HttpClient,
 ) chan HtmlPage {
+	return StartHttpFetchersWithOptions(urlChannel, httpClient, FetcherOptions{})
+}
+
+// Same as StartHttpFetchers, configured by the given options
+func StartHttpFetchersWithOptions(
+	urlChannel chan url.URL,
+	httpClient HttpClient,
+	options FetcherOptions,
+) chan HtmlPage {
 
 	respChan := make(chan HtmlPage)
 
@@ -85,7 +288,7 @@ func StartHttpFetchers(
 		for toFetch := range urlChannel {
 			wg.Add(1)
 			// TODO evaluate the opportunity to introduce a pool of go-routines
-			go httpFetch(httpClient, toFetch, respChan, &wg)
+			go httpFetch(httpClient, toFetch, respChan, &wg, options)
 		}
 
 		log.Info("Upstream channel down, preparing shutdown")
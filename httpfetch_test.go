@@ -35,6 +35,27 @@ func (client *ComposedHttpClientMock) Get (address url.URL) (resp *http.Response
 	return client.clients[address].Get(address)
 }
 
+// Answers 304 when the request carries the expected ETag
+type ConditionalHttpClientMock struct {
+	etag string
+	headers chan http.Header
+}
+
+func (client *ConditionalHttpClientMock) Get (address url.URL) (resp *http.Response, err error) {
+	return client.GetWithHeader(address, make(http.Header))
+}
+
+func (client *ConditionalHttpClientMock) GetWithHeader (address url.URL, header http.Header) (resp *http.Response, err error) {
+	client.headers <- header
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("ETag", client.etag)
+	if header.Get("If-None-Match") == client.etag {
+		recorder.WriteHeader(http.StatusNotModified)
+	} else {
+		recorder.WriteString("<html>changed</html>")
+	}
+	return recorder.Result(), nil
+}
 
 var _ = Describe("StartHttpFetchers", func() {
 
@@ -77,10 +98,10 @@ var _ = Describe("StartHttpFetchers", func() {
 		res := []HtmlPage{res1, res2}
 
 		Expect(res).To(ContainElement(HtmlPage{
-			*url1, []byte(page1),
+			Address: *url1, Bytes: []byte(page1), Response: ResponseInfo{StatusCode: 200},
 		}))
 		Expect(res).To(ContainElement(HtmlPage{
-			*url2, []byte(page2),
+			Address: *url2, Bytes: []byte(page2), Response: ResponseInfo{StatusCode: 200},
 		}))
 
 		close(inChan)
@@ -121,10 +142,10 @@ var _ = Describe("StartHttpFetchers", func() {
 		res := []HtmlPage{res1, res2}
 
 		Expect(res).To(ContainElement(HtmlPage{
-			*url1, make([]byte, 0),
+			Address: *url1, Bytes: make([]byte, 0), Failed: true,
 		}))
 		Expect(res).To(ContainElement(HtmlPage{
-			*url2, []byte(page2),
+			Address: *url2, Bytes: []byte(page2), Response: ResponseInfo{StatusCode: 200},
 		}))
 
 		close(inChan)
@@ -135,4 +156,126 @@ var _ = Describe("StartHttpFetchers", func() {
 
 	})
 
+	It("should reuse the previous links when the page was not modified", func(done Done) {
+		previous := make(MemoryPageStore)
+		previous.Put(HtmlPageLinks{
+			Address: *url1,
+			LinksTo: []url.URL{*url2},
+			Response: ResponseInfo{StatusCode: 200, ETag: `"v1"`},
+		})
+
+		client := ConditionalHttpClientMock{`"v1"`, make(chan http.Header, 1)}
+
+		inChan := make(chan url.URL, 1)
+
+		outChan := StartHttpFetchersWithOptions(inChan, &client, FetcherOptions{Previous: previous})
+
+		inChan <- *url1
+
+		res := <-outChan
+
+		Expect((<-client.headers).Get("If-None-Match")).To(Equal(`"v1"`))
+		Expect(res.Bytes).To(BeEmpty())
+		Expect(res.Response).To(Equal(ResponseInfo{StatusCode: 200, ETag: `"v1"`, NotModified: true}))
+		Expect(res.Previous.LinksTo).To(Equal([]url.URL{*url2}))
+
+		close(inChan)
+		Eventually(outChan).Should(BeClosed())
+
+		close(done)
+	})
+
+	It("should download again the pages only requested for their status", func(done Done) {
+		previous := make(MemoryPageStore)
+		previous.Put(HtmlPageLinks{
+			Address: *url1,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 200, ETag: `"v1"`, StatusOnly: true},
+		})
+
+		client := ConditionalHttpClientMock{`"v1"`, make(chan http.Header, 1)}
+
+		inChan := make(chan url.URL, 1)
+
+		outChan := StartHttpFetchersWithOptions(inChan, &client, FetcherOptions{Previous: previous})
+
+		inChan <- *url1
+
+		res := <-outChan
+
+		Expect((<-client.headers).Get("If-None-Match")).To(BeEmpty())
+		Expect(res.Previous).To(BeNil())
+		Expect(res.Response.NotModified).To(BeFalse())
+		Expect(res.Bytes).To(Equal([]byte("<html>changed</html>")))
+
+		close(inChan)
+		Eventually(outChan).Should(BeClosed())
+
+		close(done)
+	})
+
+	It("should download the page again when it changed", func(done Done) {
+		previous := make(MemoryPageStore)
+		previous.Put(HtmlPageLinks{
+			Address: *url1,
+			LinksTo: []url.URL{*url2},
+			Response: ResponseInfo{StatusCode: 200, ETag: `"v1"`},
+		})
+
+		client := ConditionalHttpClientMock{`"v2"`, make(chan http.Header, 1)}
+
+		inChan := make(chan url.URL, 1)
+
+		outChan := StartHttpFetchersWithOptions(inChan, &client, FetcherOptions{Previous: previous})
+
+		inChan <- *url1
+
+		res := <-outChan
+
+		Expect(res).To(Equal(HtmlPage{
+			Address: *url1,
+			Bytes: []byte("<html>changed</html>"),
+			Response: ResponseInfo{StatusCode: 200, ETag: `"v2"`},
+		}))
+
+		close(inChan)
+		Eventually(outChan).Should(BeClosed())
+
+		close(done)
+	})
+
+	It("should measure the requests when asked to", func(done Done) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(page1))
+		}))
+		defer server.Close()
+		serverUrl, _ := url.Parse(server.URL)
+
+		inChan := make(chan url.URL, 1)
+		outChan := StartHttpFetchersWithOptions(inChan, &DefaultHttpClient{}, FetcherOptions{Timings: true})
+
+		inChan <- *serverUrl
+		res := <-outChan
+
+		Expect(res.Response.Timing).NotTo(BeNil())
+		Expect(res.Response.Timing.Connect).To(BeNumerically(">", 0))
+		Expect(res.Response.Timing.FirstByte).To(BeNumerically(">", 0))
+		Expect(res.Response.Timing.Total).To(BeNumerically(">=", res.Response.Timing.FirstByte))
+		Expect(res.Response.Timing.Bytes).To(Equal(int64(len(page1))))
+
+		// clients that can't be traced only get the total
+		client := HttpClientMock{map[url.URL]string{*url1: page1}}
+		untracedChan := make(chan url.URL, 1)
+		untraced := StartHttpFetchersWithOptions(untracedChan, &client, FetcherOptions{Timings: true})
+		untracedChan <- *url1
+		res = <-untraced
+
+		Expect(res.Response.Timing.FirstByte).To(BeZero())
+		Expect(res.Response.Timing.Total).To(BeNumerically(">", 0))
+
+		close(inChan)
+		close(untracedChan)
+		close(done)
+	})
+
 })
@@ -3,6 +3,9 @@ package main
 import (
 	"net/url"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"github.com/PuerkitoBio/goquery"
 	log "github.com/sirupsen/logrus"
 )
@@ -11,17 +14,58 @@ import (
 type HtmlPageLinks struct {
 	Address url.URL
 	LinksTo []url.URL
+	Response ResponseInfo
+	Info PageInfo
+}
+
+// What we know about the content of a page besides its links
+type PageInfo struct {
+	Title string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// absolute address of the rel=canonical link, if any
+	Canonical string `json:"canonical,omitempty"`
+	// rel=alternate links carrying an hreflang attribute
+	Alternates []Alternate `json:"alternates,omitempty"`
+	H1s []string `json:"h1s,omitempty"`
+	Lang string `json:"lang,omitempty"`
+	// content of the robots meta tag (e.g. "noindex, follow")
+	Robots string `json:"robots,omitempty"`
+	// words of visible text (scripts and styles excluded)
+	WordCount int `json:"word_count"`
+	// sha256 of the visible text with whitespace collapsed, equal for pages
+	// showing the same content regardless of their markup
+	ContentHash string `json:"content_hash,omitempty"`
+	// SimHash fingerprint of the visible text, close for similar pages
+	SimHash uint64 `json:"simhash,omitempty"`
+	// why the page looks like an error page despite its status, see DetectSoft404s
+	Soft404 string `json:"soft_404,omitempty"`
 }
 
 // Given a html page it will parse it, extract the links and send them downstream
-func extractLinks(page HtmlPage, output chan HtmlPageLinks) {
+func extractLinks(page HtmlPage, output chan HtmlPageLinks, options ExtractorOptions) {
+	if page.Failed {
+		// the mapper still needs to know the request is over
+		output <- HtmlPageLinks{page.Address, make([]url.URL, 0), page.Response, PageInfo{}}
+		return
+	}
+
+	if page.Response.NotModified {
+		log.Debug("Document not modified, reusing links ", page.Address)
+		links := HtmlPageLinks{page.Address, page.Previous.LinksTo, page.Response, page.Previous.Info}
+		options.Hooks.Parsed(links)
+		output <- links
+		return
+	}
+
 	log.Debug("Parsing document ", page.Address)
 
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page.Bytes))
 
 	if err != nil {
 		log.Error("Can't parse document", page.Address, err)
-		output <- HtmlPageLinks{page.Address, make([]url.URL, 0)}
+		options.Metrics.CountError(ErrorParse)
+		options.Hooks.Failed(page.Address, err)
+		output <- HtmlPageLinks{page.Address, make([]url.URL, 0), page.Response, PageInfo{}}
 		return
 	}
 
@@ -50,21 +94,113 @@ func extractLinks(page HtmlPage, output chan HtmlPageLinks) {
 
 	log.Debug("Links extracted ", page.Address, " ", links)
 
-	output <- HtmlPageLinks{page.Address, links}
+	parsed := HtmlPageLinks{page.Address, links, page.Response, extractPageInfo(doc, page.Address)}
+	options.Hooks.Parsed(parsed)
+	output <- parsed
+
+}
 
+// A version of the page in another language (hreflang)
+type Alternate struct {
+	Lang string `json:"lang"`
+	Href string `json:"href"`
+}
+
+// The canonical and alternate addresses of the page
+func (info PageInfo) RelatedAddresses() []url.URL {
+	raw := make([]string, 0, len(info.Alternates)+1)
+	if info.Canonical != "" {
+		raw = append(raw, info.Canonical)
+	}
+	for _, alternate := range info.Alternates {
+		raw = append(raw, alternate.Href)
+	}
+
+	related := make([]url.URL, 0, len(raw))
+	for _, r := range raw {
+		if address, err := url.Parse(r); err == nil {
+			related = append(related, *address)
+		}
+	}
+	return related
+}
+
+// Collects the metadata of a parsed document, must run after the links have
+// been extracted since it strips scripts and styles from the document
+func extractPageInfo(doc *goquery.Document, address url.URL) PageInfo {
+	info := PageInfo{
+		Title: strings.TrimSpace(doc.Find("title").First().Text()),
+		H1s: make([]string, 0),
+	}
+
+	info.Description, _ = doc.Find(`meta[name="description" i]`).First().Attr("content")
+	info.Description = strings.TrimSpace(info.Description)
+
+	if href, ok := doc.Find(`link[rel="canonical" i]`).First().Attr("href"); ok {
+		if canonical, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			info.Canonical = address.ResolveReference(canonical).String()
+		} else {
+			log.Warn("Can't parse canonical address ", href, err)
+		}
+	}
+
+	doc.Find(`link[rel="alternate" i][hreflang]`).Each(func(_ int, elem *goquery.Selection) {
+		lang, _ := elem.Attr("hreflang")
+		href, _ := elem.Attr("href")
+		alternate, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			log.Warn("Can't parse alternate address ", href, err)
+			return
+		}
+		info.Alternates = append(info.Alternates, Alternate{
+			strings.TrimSpace(lang),
+			address.ResolveReference(alternate).String(),
+		})
+	})
+
+	doc.Find("h1").Each(func(_ int, elem *goquery.Selection) {
+		info.H1s = append(info.H1s, strings.TrimSpace(elem.Text()))
+	})
+
+	info.Lang, _ = doc.Find("html").First().Attr("lang")
+
+	info.Robots, _ = doc.Find(`meta[name="robots" i]`).First().Attr("content")
+	info.Robots = strings.TrimSpace(info.Robots)
+
+	doc.Find("script, style, noscript, template").Remove()
+	words := strings.Fields(doc.Find("body").Text())
+	info.WordCount = len(words)
+	hash := sha256.Sum256([]byte(strings.Join(words, " ")))
+	info.ContentHash = hex.EncodeToString(hash[:])
+	info.SimHash = SimHash(words)
+
+	return info
 }
 
 // Reads pages from the given chan and outputs contained links on the
 // returned chan
 func StartLinkExtractor(requests chan HtmlPage) chan HtmlPageLinks {
+	return StartLinkExtractorWithOptions(requests, ExtractorOptions{})
+}
+
+// Tunables for StartLinkExtractorWithOptions
+type ExtractorOptions struct {
+	Metrics *Metrics
+	// notified of each page parsed or failed
+	Hooks *CrawlHooks
+}
+
+// Same as StartLinkExtractor, configured by the given options
+func StartLinkExtractorWithOptions(requests chan HtmlPage, options ExtractorOptions) chan HtmlPageLinks {
 
 	respChan := make(chan HtmlPageLinks)
 
 	go func() {
 		for toParse := range requests {
+			options.Metrics.AddStage(StageAwaitingParse, -1)
 			// I expect extractLinks to be much faster than the http fetcher,
 			// so using a dedicated go routine should not be necessary here
-			extractLinks(toParse, respChan)
+			extractLinks(toParse, respChan, options)
 		}
 
 		log.Info("Upstream closed, closing downstream")
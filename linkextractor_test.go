@@ -7,6 +7,12 @@ import (
 	"net/url"
 )
 
+// the links are what most tests are about, the metadata is checked separately
+func withoutInfo(links HtmlPageLinks) HtmlPageLinks {
+	links.Info = PageInfo{}
+	return links
+}
+
 var _ = Describe("StartLinkExtractor", func() {
 
 	var (
@@ -29,13 +35,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentWithOneLink}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentWithOneLink}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*monzoUrl},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*monzoUrl},
 		}))
 
 		close(done)
@@ -49,13 +55,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentWithRelativeLink}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentWithRelativeLink}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*aboutPageUrl},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl},
 		}))
 
 		close(done)
@@ -72,13 +78,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentWithMoreLinks}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentWithMoreLinks}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*monzoUrl, *pageUrl, *aboutPageUrl},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*monzoUrl, *pageUrl, *aboutPageUrl},
 		}))
 
 		close(done)
@@ -95,13 +101,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentWithMoreLinks}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentWithMoreLinks}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*pageUrl},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*pageUrl},
 		}))
 
 		close(done)
@@ -121,13 +127,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentWithNestedLink}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentWithNestedLink}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*monzoUrl},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*monzoUrl},
 		}))
 
 		close(done)
@@ -145,13 +151,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentWithCommentedLink}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentWithCommentedLink}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*monzoUrl},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*monzoUrl},
 		}))
 
 		close(done)
@@ -165,13 +171,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentWithNoLink}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentWithNoLink}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{},
 		}))
 
 		close(done)
@@ -185,13 +191,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentEmpty}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentEmpty}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{},
 		}))
 
 		close(done)
@@ -205,13 +211,13 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentNil}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentNil}
 
 		res := <-output
 
-		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{},
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{},
 		}))
 
 		close(done)
@@ -225,17 +231,109 @@ var _ = Describe("StartLinkExtractor", func() {
 
 		output := StartLinkExtractor(pages)
 
-		pages <- HtmlPage{*pageUrl, documentNotParsable}
+		pages <- HtmlPage{Address: *pageUrl, Bytes: documentNotParsable}
+
+		res := <-output
+
+		Expect(withoutInfo(res)).To(Equal(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{},
+		}))
+
+		close(done)
+
+	})
+
+	It("should forward the known links of pages that were not modified", func(done Done) {
+		pages := make(chan HtmlPage)
+
+		output := StartLinkExtractor(pages)
+
+		response := ResponseInfo{StatusCode: 200, ETag: `"v1"`, NotModified: true}
+		pages <- HtmlPage{
+			Address: *pageUrl,
+			Bytes: make([]byte, 0),
+			Response: response,
+			Previous: &HtmlPageLinks{
+				Address: *pageUrl,
+				LinksTo: []url.URL{*aboutPageUrl},
+				Info: PageInfo{Title: "Google"},
+			},
+		}
 
 		res := <-output
 
 		Expect(res).To(Equal(HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{},
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl},
+			Response: response,
+			Info: PageInfo{Title: "Google"},
 		}))
 
 		close(done)
+	})
+
+	It("should extract the page metadata", func(done Done) {
+		document := ([]byte)(`
+			<html lang="en-GB">
+			<head>
+				<title> About us </title>
+				<meta name="Description" content="Who we are">
+				<meta name="robots" content="noindex, follow">
+				<link rel="canonical" href="/about">
+				<link rel="alternate" hreflang="it" href="https://www.google.it/about">
+				<link rel="alternate" hreflang="x-default" href="/about">
+				<style>body { color: red }</style>
+			</head>
+			<body>
+				<h1>About</h1>
+				<p>We   make
+				search engines</p>
+				<h1>Contacts</h1>
+				<script>var ignored = "by the word count"</script>
+			</body>
+			</html>
+		`)
+
+		pages := make(chan HtmlPage)
+
+		output := StartLinkExtractor(pages)
+
+		pages <- HtmlPage{Address: *pageUrl, Bytes: document}
+
+		res := <-output
+
+		Expect(res.Info.Title).To(Equal("About us"))
+		Expect(res.Info.Description).To(Equal("Who we are"))
+		Expect(res.Info.Canonical).To(Equal(aboutPageUrl.String()))
+		Expect(res.Info.Alternates).To(Equal([]Alternate{
+			{Lang: "it", Href: "https://www.google.it/about"},
+			{Lang: "x-default", Href: aboutPageUrl.String()},
+		}))
+		Expect(res.Info.H1s).To(Equal([]string{"About", "Contacts"}))
+		Expect(res.Info.Lang).To(Equal("en-GB"))
+		Expect(res.Info.Robots).To(Equal("noindex, follow"))
+		Expect(res.Info.WordCount).To(Equal(6))
+		Expect(res.Info.ContentHash).To(HaveLen(64))
+
+		close(done)
+	})
+
+	It("should give the same content hash to pages differing only in markup", func(done Done) {
+		pages := make(chan HtmlPage)
 
+		output := StartLinkExtractor(pages)
+
+		pages <- HtmlPage{Address: *pageUrl, Bytes: ([]byte)(`<p>same words</p>`)}
+		first := <-output
+		pages <- HtmlPage{Address: *aboutPageUrl, Bytes: ([]byte)(`<div><b>same</b>
+			words</div>`)}
+		second := <-output
+
+		Expect(first.Info.ContentHash).To(Equal(second.Info.ContentHash))
+		Expect(first.Info.SimHash).To(Equal(second.Info.SimHash))
+
+		close(done)
 	})
 
 })
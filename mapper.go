@@ -11,31 +11,136 @@ func isSameHost(root *url.URL, other *url.URL) bool {
 	return other.Host == root.Host
 }
 
+// The addresses the mapper is allowed to request: the ones pertaining to
+// the host of any of the seeds
+type HostScope []url.URL
+
+func (scope HostScope) Contains(address url.URL) bool {
+	for _, seed := range scope {
+		if isSameHost(&seed, &address) {
+			return true
+		}
+	}
+	return false
+}
+
 // The MapSite will start by pushing the specified root down the addressChan,
 // will wait the related links on the link chan and (if those links are from
 // the same host as the root and not already processed) will send those on the addressChan too.
 // Once it has retrieved all the pages in the tree for the specified root, it will return a map
 // containing as key the various pages and as value the list of the pages it links to.
 func MapSite(root url.URL, addressChan chan url.URL, linksChan chan HtmlPageLinks) PagesMap {
-	state := initState()
+	// the in memory implementations never fail
+	pages, _ := MapSiteWithOptions(root, addressChan, linksChan, MapperOptions{})
+	siteMap, _ := ToPagesMap(pages)
+	return siteMap
+}
+
+// Tunables for MapSiteWithOptions, the zero value keeps the whole state in memory
+// and requests every link as soon as it is found (the MapSite behaviour)
+type MapperOptions struct {
+	Frontier Frontier
+	Seen     SeenSet
+	Pages    PageStore
+	// maximum number of addresses pushed down the addressChan that have not
+	// come back through the linksChan yet, 0 means no limit
+	MaxInFlight int
+	// maximum number of addresses requested during the whole crawl, 0 means
+	// no limit. Which pages make the cut depends on the Frontier ordering
+	MaxPages int
+	// crawled along with the root (e.g. sections not linked from the home page),
+	// their hosts are in scope as well
+	Seeds []url.URL
+	// when set, the links it quarantines are not requested (the seeds are
+	// always requested)
+	Traps *TrapDetector
+	// when set, links are cleaned of the parameters it filters before being
+	// stored or requested
+	Parameters *ParameterFilter
+	// updated with the queue length and the state errors
+	Metrics *Metrics
+	// notified of each address discovered, enqueued or skipped
+	Hooks *CrawlHooks
+}
+
+// Same as MapSite, but links are queued on the configured Frontier and at most
+// MaxInFlight of them are requested at once. In case the state can't be updated
+// (e.g. disk failures) no new address is requested, the pending ones are waited
+// for and the error is returned along with what was retrieved so far
+func MapSiteWithOptions(
+	root url.URL,
+	addressChan chan url.URL,
+	linksChan chan HtmlPageLinks,
+	options MapperOptions,
+) (PageStore, error) {
+	state := initState(options)
+	scope := HostScope(append([]url.URL{root}, options.Seeds...))
 	log.Info("Starting crawling from root ", root)
-	addressChan <- root
-	state.onRequested(root)
+	state.enqueue(FrontierEntry{root, 0})
+	for _, seed := range options.Seeds {
+		if state.shouldBeRequested(seed) {
+			state.enqueue(FrontierEntry{seed, 0})
+		}
+	}
+	state.dispatch(addressChan)
+
+	if !state.hasPending() {
+		// we could not even request the root
+		close(addressChan)
+	}
+
+	clean := func(links []url.URL) []url.URL {
+		if options.Parameters == nil {
+			return links
+		}
+		return options.Parameters.CleanLinks(links)
+	}
+
+	follow := func(from url.URL, link url.URL, depth int, statusOnly bool) {
+		options.Hooks.Discovered(from, link)
+		reason := state.skipReason(scope, link, statusOnly)
+		if reason == SkipSeen && !statusOnly && state.promote(FrontierEntry{link, depth}) {
+			log.Debug("Following ", link, " as a link")
+			return
+		}
+		if reason == "" && options.Traps != nil && options.Traps.Quarantine(link) {
+			log.Debug("Quarantining ", link)
+			state.markSeen(link)
+			reason = SkipTrap
+		}
+		if reason != "" {
+			log.Debug("Skipping ", link, " ", reason)
+			options.Hooks.Skipped(link, reason)
+			return
+		}
+		log.Debug("Queueing ", link)
+		if statusOnly {
+			state.enqueueStatusOnly(FrontierEntry{link, depth})
+		} else {
+			state.enqueue(FrontierEntry{link, depth})
+		}
+	}
 
 	for links := range linksChan {
+		links.LinksTo = clean(links.LinksTo)
+
 		// update the state (mapper is single threaded, no sync needed)
-		state.onRetrieved(links.Address, links.LinksTo)
-
-		for _, link := range links.LinksTo {
-			if isSameHost(&root, &link) && state.shouldBeRequested(link) {
-				log.Debug("Requesting ", link)
-				addressChan <- link
-				state.onRequested(link)
-			} else {
-				log.Debug("Skipping ", link)
+		depth, statusOnly := state.onRetrieved(links)
+
+		if !statusOnly {
+			for _, link := range links.LinksTo {
+				follow(links.Address, link, depth+1, false)
+			}
+			// canonical and alternate versions are not links a visitor can follow,
+			// they are only requested for their status in order to validate them
+			for _, link := range clean(links.Info.RelatedAddresses()) {
+				follow(links.Address, link, depth+1, true)
 			}
 		}
 
+		state.dispatch(addressChan)
+		options.Metrics.SetQueue(state.frontier.Len()+len(state.statusQueue), len(state.inFlight))
+
 		if !state.hasPending() {
 			// all that we pushed down the addressChan has come back
 			// through the linksChan, there is nothing else for us to do
@@ -48,45 +153,223 @@ func MapSite(root url.URL, addressChan chan url.URL, linksChan chan HtmlPageLink
 
 	}
 
-	return state.retrieved
+	return state.retrieved, state.err
 
 }
 
-type PendingMap map[url.URL]bool
 type PagesMap map[url.URL][]url.URL
 
 // Stores the current state of the mapper
 type State struct {
-	pending PendingMap
-	retrieved PagesMap
+	frontier  Frontier
+	seen      SeenSet
+	retrieved PageStore
+	// depth of the addresses pushed down the addressChan and not back yet
+	inFlight    map[url.URL]int
+	maxInFlight int
+	requested   int
+	maxPages    int
+	// first error met while updating the state, stops the crawling
+	err error
+	metrics *Metrics
+	hooks   *CrawlHooks
+	// canonical and alternate addresses requested for their status only (not
+	// found as links so far), true once retrieved. Kept for the whole crawl in
+	// case a link to them turns up later
+	statusOnly map[url.URL]bool
+	// the status only addresses not requested yet, they are requested once the
+	// frontier is empty and don't count towards maxPages
+	statusQueue []FrontierEntry
+}
+
+func initState(options MapperOptions) State {
+	state := State{
+		frontier:    options.Frontier,
+		seen:        options.Seen,
+		retrieved:   options.Pages,
+		inFlight:    make(map[url.URL]int),
+		maxInFlight: options.MaxInFlight,
+		maxPages:    options.MaxPages,
+		metrics:     options.Metrics,
+		hooks:       options.Hooks,
+		statusOnly:  make(map[url.URL]bool),
+	}
+	if state.frontier == nil {
+		state.frontier = NewMemoryFrontier(nil)
+	}
+	if state.seen == nil {
+		state.seen = make(MemorySeenSet)
+	}
+	if state.retrieved == nil {
+		state.retrieved = make(MemoryPageStore)
+	}
+	return state
+}
+
+func (state *State) fail(err error) {
+	log.Error("Can't update the mapper state ", err)
+	state.metrics.CountError(ErrorState)
+	if state.err == nil {
+		state.err = err
+	}
+}
+
+func (state *State) enqueue(entry FrontierEntry) {
+	if !state.markSeen(entry.Address) {
+		return
+	}
+	if err := state.frontier.Push(entry); err != nil {
+		state.fail(err)
+		return
+	}
+	state.hooks.Enqueued(entry)
+}
+
+func (state *State) enqueueStatusOnly(entry FrontierEntry) {
+	if !state.markSeen(entry.Address) {
+		return
+	}
+	state.statusOnly[entry.Address] = false
+	state.statusQueue = append(state.statusQueue, entry)
+	state.hooks.Enqueued(entry)
+}
+
+// the address won't be considered again, false if the state could not be updated
+func (state *State) markSeen(address url.URL) bool {
+	if state.err != nil {
+		return false
+	}
+	if err := state.seen.Add(address); err != nil {
+		state.fail(err)
+		return false
+	}
+	return true
+}
+
+// false once an error occurred or the page limit has been reached
+func (state *State) canRequest() bool {
+	return state.err == nil && (state.maxPages <= 0 || state.requested < state.maxPages)
+}
+
+// pushes queued addresses down the addressChan until the in flight limit is
+// reached, the status only ones once the frontier is empty (or the page limit
+// reached)
+func (state *State) dispatch(addressChan chan url.URL) {
+	for state.err == nil && (state.maxInFlight <= 0 || len(state.inFlight) < state.maxInFlight) {
+		next, ok := FrontierEntry{}, false
+		if state.canRequest() {
+			var err error
+			next, ok, err = state.frontier.Pop()
+			if err != nil {
+				state.fail(err)
+				return
+			}
+		}
+		if !ok {
+			if len(state.statusQueue) == 0 {
+				return
+			}
+			next = state.statusQueue[0]
+			state.statusQueue = state.statusQueue[1:]
+		}
+		addressChan <- next.Address
+		state.onRequested(next)
+	}
+}
+
+func (state *State) onRequested(entry FrontierEntry) {
+	log.Print("Fetching ", entry.Address.String())
+	state.inFlight[entry.Address] = entry.Depth
+	if _, statusOnly := state.statusOnly[entry.Address]; !statusOnly {
+		state.requested++
+	}
 }
 
-func initState() State {
-	return State{
-		make(map[url.URL]bool),
-		make(map[url.URL][]url.URL),
+// returns the depth the page was found at and whether it was requested for
+// its status only, in which case its links are not part of the crawl
+func (state *State) onRetrieved(page HtmlPageLinks) (int, bool) {
+	log.Print("Fetched ", len(page.LinksTo), " ", page.Address.String())
+	depth := state.inFlight[page.Address]
+	delete(state.inFlight, page.Address)
+	_, statusOnly := state.statusOnly[page.Address]
+	if statusOnly {
+		state.statusOnly[page.Address] = true
+		page.LinksTo = make([]url.URL, 0)
+		page.Response.StatusOnly = true
+	}
+	if err := state.retrieved.Put(page); err != nil {
+		state.fail(err)
 	}
+	return depth, statusOnly
 }
 
-func (state *State) onRequested(url url.URL) {
-	log.Print("Fetching ", url.String())
-	state.pending[url] = true
+// a link to an address so far requested for its status only: it becomes part
+// of the crawl, requested again if it was already retrieved. False if the
+// address is not a status only one
+func (state *State) promote(entry FrontierEntry) bool {
+	retrieved, found := state.statusOnly[entry.Address]
+	if !found {
+		return false
+	}
+	delete(state.statusOnly, entry.Address)
+	if retrieved || state.unqueueStatusOnly(entry.Address) {
+		if err := state.frontier.Push(entry); err != nil {
+			state.fail(err)
+			return true
+		}
+		state.hooks.Enqueued(entry)
+	}
+	return true
 }
 
-func (state *State) onRetrieved(url url.URL, links []url.URL) {
-	log.Print("Fetched ", len(links), " ", url.String())
-	delete(state.pending, url)
-	state.retrieved[url] = links
+// removes the address from the status only queue, false if it was not there
+// (in flight or retrieved)
+func (state *State) unqueueStatusOnly(address url.URL) bool {
+	for i, entry := range state.statusQueue {
+		if entry.Address == address {
+			state.statusQueue = append(state.statusQueue[:i], state.statusQueue[i+1:]...)
+			return true
+		}
+	}
+	return false
 }
 
 func (state *State) shouldBeRequested(url url.URL) bool {
-	_, isPending := state.pending[url]
-	_, isRetrieved := state.retrieved[url]
-	return !isPending && !isRetrieved
+	if !state.canRequest() {
+		return false
+	}
+	seen, err := state.seen.Contains(url)
+	if err != nil {
+		state.fail(err)
+		return false
+	}
+	return !seen
 }
 
+// why the link won't be requested, empty if it should be. Status only
+// addresses are not subject to the page limit
+func (state *State) skipReason(scope HostScope, link url.URL, statusOnly bool) string {
+	if !scope.Contains(link) {
+		return SkipOutOfScope
+	}
+	if state.err != nil || (!statusOnly && !state.canRequest()) {
+		return SkipLimit
+	}
+	seen, err := state.seen.Contains(link)
+	if err != nil {
+		state.fail(err)
+		return SkipLimit
+	}
+	if seen {
+		return SkipSeen
+	}
+	return ""
+}
+
+// true while some address is in flight or still queued (and allowed to be requested)
 func (state *State) hasPending() bool {
-	return len(state.pending) != 0
+	return len(state.inFlight) != 0 || (state.canRequest() && state.frontier.Len() != 0) ||
+		(state.err == nil && len(state.statusQueue) != 0)
 }
 
 // struct used to simulate the recursion stack
@@ -97,6 +380,12 @@ type StackElement struct {
 
 // Prints the sitemap
 func (pages PagesMap) Print(root url.URL) {
+	pages.PrintFrom([]url.URL{root})
+}
+
+// Prints the sitemap starting from each of the given roots in turn, branches
+// already printed under a previous root are only referenced
+func (pages PagesMap) PrintFrom(roots []url.URL) {
 	// recursive version might be more concise, but if I understood correctly
 	// go does not interpret tail recursion so it would risk a stack overflow,
 	// let's iterate (assuming max slice size > max stack size)
@@ -105,7 +394,9 @@ func (pages PagesMap) Print(root url.URL) {
 	stack := make([]StackElement, 0)
 
 	// start from the root links and go through the map
-	stack = append(stack, StackElement{root, 0})
+	for _, root := range roots {
+		stack = append(stack, StackElement{root, 0})
+	}
 
 	for len(stack) > 0 {
 		// pop the head
@@ -51,28 +51,28 @@ var _ = Describe("Mapper", func() {
 		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
 
 		linksChan <- HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*aboutPageUrl, *otherPageUrl},
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl, *otherPageUrl},
 		}
 
 		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
 		Eventually(addressChan).Should(Receive(Equal(*otherPageUrl)))
 
 		linksChan <- HtmlPageLinks{
-			*aboutPageUrl,
-			[]url.URL{},
+			Address: *aboutPageUrl,
+			LinksTo: []url.URL{},
 		}
 
 		linksChan <- HtmlPageLinks{
-			*otherPageUrl,
-			[]url.URL{*lastPageUrl},
+			Address: *otherPageUrl,
+			LinksTo: []url.URL{*lastPageUrl},
 		}
 
 		Eventually(addressChan).Should(Receive(Equal(*lastPageUrl)))
 
 		linksChan <- HtmlPageLinks{
-			*lastPageUrl,
-			[]url.URL{},
+			Address: *lastPageUrl,
+			LinksTo: []url.URL{},
 		}
 
 		Eventually(addressChan).Should(BeClosed())
@@ -97,8 +97,8 @@ var _ = Describe("Mapper", func() {
 		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
 
 		linksChan <- HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*monzoUrl},
+			Address: *pageUrl,
+			LinksTo: []url.URL{*monzoUrl},
 		}
 
 		Consistently(addressChan).ShouldNot(Receive())
@@ -125,15 +125,15 @@ var _ = Describe("Mapper", func() {
 		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
 
 		linksChan <- HtmlPageLinks{
-			*pageUrl,
-			[]url.URL{*aboutPageUrl},
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl},
 		}
 
 		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
 
 		linksChan <- HtmlPageLinks{
-			*aboutPageUrl,
-			[]url.URL{*pageUrl},
+			Address: *aboutPageUrl,
+			LinksTo: []url.URL{*pageUrl},
 		}
 
 		Consistently(addressChan).ShouldNot(Receive())
@@ -143,4 +143,306 @@ var _ = Describe("Mapper", func() {
 		close(linksChan)
 	})
 
-})
\ No newline at end of file
+	It("should not request more than MaxInFlight addresses at once", func(done Done) {
+		go func() {
+			res, err := MapSiteWithOptions(*pageUrl, addressChan, linksChan, MapperOptions{MaxInFlight: 1})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Len()).To(Equal(3))
+
+			close(done)
+		}()
+
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+
+		linksChan <- HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl, *otherPageUrl},
+		}
+
+		// the second link waits in the frontier until the first comes back
+		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
+		Consistently(addressChan).ShouldNot(Receive())
+
+		linksChan <- HtmlPageLinks{
+			Address: *aboutPageUrl,
+			LinksTo: []url.URL{},
+		}
+
+		Eventually(addressChan).Should(Receive(Equal(*otherPageUrl)))
+
+		linksChan <- HtmlPageLinks{
+			Address: *otherPageUrl,
+			LinksTo: []url.URL{},
+		}
+
+		Eventually(addressChan).Should(BeClosed())
+
+		close(linksChan)
+	})
+
+	It("should stop requesting pages once MaxPages is reached", func(done Done) {
+		go func() {
+			res, err := MapSiteWithOptions(*pageUrl, addressChan, linksChan, MapperOptions{MaxPages: 2})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Len()).To(Equal(2))
+
+			close(done)
+		}()
+
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+
+		linksChan <- HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl, *otherPageUrl},
+		}
+
+		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
+		Consistently(addressChan).ShouldNot(Receive())
+
+		linksChan <- HtmlPageLinks{
+			Address: *aboutPageUrl,
+			LinksTo: []url.URL{},
+		}
+
+		Eventually(addressChan).Should(BeClosed())
+
+		close(linksChan)
+	})
+
+	It("should request canonical addresses after the links, outside of MaxPages", func(done Done) {
+		go func() {
+			res, err := MapSiteWithOptions(*pageUrl, addressChan, linksChan, MapperOptions{
+				Frontier: NewMemoryFrontier(DfsOrdering),
+				MaxInFlight: 1,
+				MaxPages: 3,
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Len()).To(Equal(4))
+
+			close(done)
+		}()
+
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+		linksChan <- HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*aboutPageUrl, *otherPageUrl},
+			Info: PageInfo{Canonical: lastPageUrl.String()},
+		}
+
+		// the canonical was found last, dfs would pop it first
+		Eventually(addressChan).Should(Receive(Equal(*otherPageUrl)))
+		linksChan <- HtmlPageLinks{Address: *otherPageUrl, LinksTo: []url.URL{}}
+		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
+		linksChan <- HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{}}
+
+		// the page limit is reached, validating the canonical doesn't count
+		Eventually(addressChan).Should(Receive(Equal(*lastPageUrl)))
+		linksChan <- HtmlPageLinks{Address: *lastPageUrl, LinksTo: []url.URL{}}
+
+		Eventually(addressChan).Should(BeClosed())
+		close(linksChan)
+	})
+
+	It("should start from every seed and follow links on their hosts", func(done Done) {
+		monzoJobsUrl, _ := url.Parse("https://www.monzo.com/jobs")
+
+		go func() {
+			res, err := MapSiteWithOptions(*pageUrl, addressChan, linksChan, MapperOptions{
+				Seeds: []url.URL{*monzoUrl, *pageUrl},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Len()).To(Equal(3))
+
+			close(done)
+		}()
+
+		// the root is not requested twice even if listed among the seeds
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+		Eventually(addressChan).Should(Receive(Equal(*monzoUrl)))
+
+		linksChan <- HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{},
+		}
+
+		linksChan <- HtmlPageLinks{
+			Address: *monzoUrl,
+			LinksTo: []url.URL{*monzoJobsUrl},
+		}
+
+		Eventually(addressChan).Should(Receive(Equal(*monzoJobsUrl)))
+
+		linksChan <- HtmlPageLinks{
+			Address: *monzoJobsUrl,
+			LinksTo: []url.URL{},
+		}
+
+		Eventually(addressChan).Should(BeClosed())
+
+		close(linksChan)
+	})
+
+	It("should request canonical and alternate addresses on the same host", func(done Done) {
+		go func() {
+			res := MapSite(*pageUrl, addressChan, linksChan)
+
+			// they are not links, they are not part of the map
+			expectedMap := map[url.URL][]url.URL {
+				*pageUrl: {},
+			}
+
+			Expect(map[url.URL][]url.URL(res)).To(Equal(expectedMap))
+
+			close(done)
+		}()
+
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+
+		linksChan <- HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{},
+			Info: PageInfo{
+				Canonical: aboutPageUrl.String(),
+				Alternates: []Alternate{
+					{Lang: "en", Href: otherPageUrl.String()},
+					{Lang: "it", Href: monzoUrl.String()},
+				},
+			},
+		}
+
+		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
+		Eventually(addressChan).Should(Receive(Equal(*otherPageUrl)))
+
+		linksChan <- HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{}}
+		linksChan <- HtmlPageLinks{Address: *otherPageUrl, LinksTo: []url.URL{}}
+
+		Eventually(addressChan).Should(BeClosed())
+
+		close(linksChan)
+	})
+
+	It("should not follow the links of canonical and alternate addresses", func(done Done) {
+		go func() {
+			res, err := MapSiteWithOptions(*pageUrl, addressChan, linksChan, MapperOptions{})
+			Expect(err).To(BeNil())
+
+			about, found, _ := res.Get(*aboutPageUrl)
+			Expect(found).To(BeTrue())
+			Expect(about.Response.StatusOnly).To(BeTrue())
+			Expect(about.Response.StatusCode).To(Equal(200))
+			Expect(about.LinksTo).To(BeEmpty())
+
+			close(done)
+		}()
+
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+		linksChan <- HtmlPageLinks{Address: *pageUrl, LinksTo: []url.URL{}, Info: PageInfo{Canonical: aboutPageUrl.String()}}
+
+		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
+		linksChan <- HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{*otherPageUrl}, Response: ResponseInfo{StatusCode: 200}}
+
+		Eventually(addressChan).Should(BeClosed())
+		close(linksChan)
+	})
+
+	It("should crawl a canonical address found as a link later on", func(done Done) {
+		go func() {
+			res := MapSite(*pageUrl, addressChan, linksChan)
+
+			Expect(map[url.URL][]url.URL(res)).To(Equal(map[url.URL][]url.URL{
+				*pageUrl: {*otherPageUrl},
+				*otherPageUrl: {*aboutPageUrl},
+				*aboutPageUrl: {},
+			}))
+
+			close(done)
+		}()
+
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+		linksChan <- HtmlPageLinks{Address: *pageUrl, LinksTo: []url.URL{*otherPageUrl}, Info: PageInfo{Canonical: aboutPageUrl.String()}}
+
+		Eventually(addressChan).Should(Receive(Equal(*otherPageUrl)))
+		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
+		linksChan <- HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{}}
+		linksChan <- HtmlPageLinks{Address: *otherPageUrl, LinksTo: []url.URL{*aboutPageUrl}}
+
+		// requested again, as a page of the crawl this time
+		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
+		linksChan <- HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{}}
+
+		Eventually(addressChan).Should(BeClosed())
+		close(linksChan)
+	})
+
+	It("should not request the addresses quarantined as traps", func(done Done) {
+		detector := NewTrapDetector(TrapOptions{MaxQueryVariants: 1})
+		firstDayUrl, _ := url.Parse("https://www.google.com/calendar?day=1")
+		secondDayUrl, _ := url.Parse("https://www.google.com/calendar?day=2")
+
+		go func() {
+			res, err := MapSiteWithOptions(*pageUrl, addressChan, linksChan, MapperOptions{Traps: detector})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Len()).To(Equal(2))
+			Expect(detector.Report().Patterns).To(HaveLen(1))
+			Expect(detector.Report().Patterns[0].Count).To(Equal(1))
+
+			close(done)
+		}()
+
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+
+		linksChan <- HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*firstDayUrl},
+		}
+
+		Eventually(addressChan).Should(Receive(Equal(*firstDayUrl)))
+
+		// the second day is linked twice but reported once
+		linksChan <- HtmlPageLinks{
+			Address: *firstDayUrl,
+			LinksTo: []url.URL{*secondDayUrl, *pageUrl, *secondDayUrl},
+		}
+
+		Eventually(addressChan).Should(BeClosed())
+
+		close(linksChan)
+	})
+
+	It("should store and request links without the filtered parameters", func(done Done) {
+		trackedUrl, _ := url.Parse("https://www.google.com/about?utm_source=home")
+
+		go func() {
+			res, err := MapSiteWithOptions(*pageUrl, addressChan, linksChan, MapperOptions{
+				Parameters: NewParameterFilter([]string{"utm_*"}),
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			home, _, _ := res.Get(*pageUrl)
+			Expect(home.LinksTo).To(Equal([]url.URL{*aboutPageUrl}))
+
+			close(done)
+		}()
+
+		Eventually(addressChan).Should(Receive(Equal(*pageUrl)))
+
+		linksChan <- HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{*trackedUrl, *aboutPageUrl},
+		}
+
+		Eventually(addressChan).Should(Receive(Equal(*aboutPageUrl)))
+
+		linksChan <- HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{}}
+
+		Eventually(addressChan).Should(BeClosed())
+
+		close(linksChan)
+	})
+
+})
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// the request failed, no status code
+	ErrorFetch = "fetch"
+	// the page could not be parsed
+	ErrorParse = "parse"
+	// the mapper state could not be updated (e.g. disk failures)
+	ErrorState = "state"
+)
+
+// Stages of the pipeline a page can be in, see AddStage
+const (
+	// being requested by a fetcher
+	StageFetching = "fetching"
+	// fetched, waiting for the extractor
+	StageAwaitingParse = "awaiting_parse"
+)
+
+// Upper bounds of the fetch latency histogram, in seconds
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Crawl metrics exposed in the Prometheus text format, see ServeHTTP. The
+// methods may be called from any goroutine and do nothing (or report nothing)
+// on a nil *Metrics, so the components can be given one unconditionally
+type Metrics struct {
+	mutex    sync.Mutex
+	fetched  map[int]uint64
+	bytes    uint64
+	buckets  []uint64
+	latency  float64
+	requests uint64
+	errors   map[string]uint64
+	pending  int
+	inFlight int
+	stages   map[string]int
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		fetched: make(map[int]uint64),
+		buckets: make([]uint64, len(latencyBuckets)),
+		errors:  make(map[string]uint64),
+		stages:  make(map[string]int),
+	}
+}
+
+// Records a page fetched with the given status
+func (metrics *Metrics) ObserveFetch(status int, bytes int, duration time.Duration) {
+	if metrics == nil {
+		return
+	}
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.fetched[status]++
+	metrics.bytes += uint64(bytes)
+	seconds := duration.Seconds()
+	metrics.latency += seconds
+	metrics.requests++
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			metrics.buckets[i]++
+		}
+	}
+}
+
+func (metrics *Metrics) CountError(kind string) {
+	if metrics == nil {
+		return
+	}
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.errors[kind]++
+}
+
+// Records the addresses waiting in the frontier and the ones being fetched
+func (metrics *Metrics) SetQueue(pending int, inFlight int) {
+	if metrics == nil {
+		return
+	}
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.pending = pending
+	metrics.inFlight = inFlight
+}
+
+// Counts the pages entering (delta 1) or leaving (delta -1) a stage of the
+// pipeline, one of the Stage constants
+func (metrics *Metrics) AddStage(stage string, delta int) {
+	if metrics == nil {
+		return
+	}
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.stages[stage] += delta
+}
+
+// Totals of the metrics at a point in time
+type MetricsSnapshot struct {
+	Fetched  int
+	Bytes    uint64
+	Errors   int
+	Pending  int
+	InFlight int
+}
+
+func (metrics *Metrics) Snapshot() MetricsSnapshot {
+	if metrics == nil {
+		return MetricsSnapshot{}
+	}
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	snapshot := MetricsSnapshot{Bytes: metrics.bytes, Pending: metrics.pending, InFlight: metrics.inFlight}
+	for _, count := range metrics.fetched {
+		snapshot.Fetched += int(count)
+	}
+	for _, count := range metrics.errors {
+		snapshot.Errors += int(count)
+	}
+	return snapshot
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Writes the metrics in the Prometheus text exposition format
+func (metrics *Metrics) WriteText(w io.Writer) error {
+	if metrics == nil {
+		return nil
+	}
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	out := bufio.NewWriter(w)
+	header := func(name string, kind string, help string) {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	header("sitemapper_pages_fetched_total", "counter", "Pages fetched, by status code.")
+	statuses := make([]int, 0, len(metrics.fetched))
+	for status := range metrics.fetched {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(out, "sitemapper_pages_fetched_total{status=\"%d\"} %d\n", status, metrics.fetched[status])
+	}
+
+	header("sitemapper_fetched_bytes_total", "counter", "Bytes of the fetched pages.")
+	fmt.Fprintf(out, "sitemapper_fetched_bytes_total %d\n", metrics.bytes)
+
+	header("sitemapper_fetch_duration_seconds", "histogram", "Time taken to fetch a page.")
+	for i, bound := range latencyBuckets {
+		fmt.Fprintf(out, "sitemapper_fetch_duration_seconds_bucket{le=\"%s\"} %d\n", formatFloat(bound), metrics.buckets[i])
+	}
+	fmt.Fprintf(out, "sitemapper_fetch_duration_seconds_bucket{le=\"+Inf\"} %d\n", metrics.requests)
+	fmt.Fprintf(out, "sitemapper_fetch_duration_seconds_sum %s\n", formatFloat(metrics.latency))
+	fmt.Fprintf(out, "sitemapper_fetch_duration_seconds_count %d\n", metrics.requests)
+
+	header("sitemapper_errors_total", "counter", "Errors met while crawling, by kind.")
+	for _, kind := range []string{ErrorFetch, ErrorParse, ErrorState} {
+		fmt.Fprintf(out, "sitemapper_errors_total{kind=%q} %d\n", kind, metrics.errors[kind])
+	}
+
+	header("sitemapper_pending_urls", "gauge", "Addresses queued in the mapper state, not requested yet.")
+	fmt.Fprintf(out, "sitemapper_pending_urls %d\n", metrics.pending)
+	header("sitemapper_in_flight_urls", "gauge", "Addresses requested and not processed yet.")
+	fmt.Fprintf(out, "sitemapper_in_flight_urls %d\n", metrics.inFlight)
+
+	header("sitemapper_stage_pages", "gauge", "Pages in each stage of the pipeline.")
+	for _, stage := range []string{StageFetching, StageAwaitingParse} {
+		fmt.Fprintf(out, "sitemapper_stage_pages{stage=%q} %d\n", stage, metrics.stages[stage])
+	}
+
+	return out.Flush()
+}
+
+func (metrics *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.WriteText(w)
+}
@@ -0,0 +1,109 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"time"
+)
+
+var _ = Describe("Metrics", func() {
+
+	var (
+		metrics *Metrics
+	)
+
+	text := func() string {
+		var out bytes.Buffer
+		Expect(metrics.WriteText(&out)).To(Succeed())
+		return out.String()
+	}
+
+	BeforeEach(func() {
+		metrics = NewMetrics()
+	})
+
+	It("should count the pages by status and their latency", func() {
+		metrics.ObserveFetch(200, 1000, 80*time.Millisecond)
+		metrics.ObserveFetch(200, 500, 2*time.Second)
+		metrics.ObserveFetch(404, 10, 40*time.Millisecond)
+
+		Expect(text()).To(ContainSubstring(
+			"# TYPE sitemapper_pages_fetched_total counter\n" +
+			"sitemapper_pages_fetched_total{status=\"200\"} 2\n" +
+			"sitemapper_pages_fetched_total{status=\"404\"} 1\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_fetched_bytes_total 1510\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_fetch_duration_seconds_bucket{le=\"0.05\"} 1\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_fetch_duration_seconds_bucket{le=\"0.1\"} 2\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_fetch_duration_seconds_bucket{le=\"2.5\"} 3\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_fetch_duration_seconds_bucket{le=\"+Inf\"} 3\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_fetch_duration_seconds_sum 2.12\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_fetch_duration_seconds_count 3\n"))
+	})
+
+	It("should expose errors, queue and stages", func() {
+		metrics.AddStage(StageFetching, 1)
+		metrics.AddStage(StageFetching, 1)
+		metrics.AddStage(StageAwaitingParse, 1)
+		metrics.AddStage(StageFetching, -1)
+		metrics.CountError(ErrorFetch)
+		metrics.SetQueue(42, 3)
+
+		Expect(text()).To(ContainSubstring("sitemapper_errors_total{kind=\"fetch\"} 1\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_errors_total{kind=\"parse\"} 0\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_pending_urls 42\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_in_flight_urls 3\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_stage_pages{stage=\"fetching\"} 1\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_stage_pages{stage=\"awaiting_parse\"} 1\n"))
+	})
+
+	It("should serve the metrics over http", func() {
+		metrics.CountError(ErrorState)
+
+		recorder := httptest.NewRecorder()
+		metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		body, _ := ioutil.ReadAll(recorder.Result().Body)
+		Expect(recorder.Result().Header.Get("Content-Type")).To(HavePrefix("text/plain"))
+		Expect(string(body)).To(ContainSubstring("sitemapper_errors_total{kind=\"state\"} 1\n"))
+	})
+
+	It("should ignore the calls on a nil value", func() {
+		var disabled *Metrics
+		disabled.ObserveFetch(200, 1, time.Second)
+		disabled.CountError(ErrorFetch)
+		disabled.SetQueue(1, 1)
+		disabled.AddStage(StageFetching, 1)
+		Expect(disabled.Snapshot()).To(Equal(MetricsSnapshot{}))
+
+		var out bytes.Buffer
+		Expect(disabled.WriteText(&out)).To(Succeed())
+		Expect(out.String()).To(BeEmpty())
+
+		recorder := httptest.NewRecorder()
+		disabled.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		Expect(recorder.Code).To(Equal(200))
+	})
+
+	It("should be updated by the fetchers", func(done Done) {
+		client := HttpClientMock{map[url.URL]string{}}
+		inChan := make(chan url.URL, 1)
+		outChan := StartHttpFetchersWithOptions(inChan, &client, FetcherOptions{Metrics: metrics})
+
+		inChan <- url.URL{Scheme: "https", Host: "www.google.com"}
+		<-outChan
+
+		Expect(text()).To(ContainSubstring("sitemapper_pages_fetched_total{status=\"200\"} 1\n"))
+		Expect(text()).To(ContainSubstring("sitemapper_stage_pages{stage=\"fetching\"} 0\n"))
+		// nobody parsed it
+		Expect(text()).To(ContainSubstring("sitemapper_stage_pages{stage=\"awaiting_parse\"} 1\n"))
+
+		close(inChan)
+		close(done)
+	})
+
+})
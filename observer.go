@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/url"
+)
+
+// Why a discovered address is not requested
+const (
+	SkipOutOfScope = "out-of-scope"
+	SkipSeen       = "seen"
+	SkipLimit      = "limit"
+	SkipTrap       = "spider-trap"
+)
+
+// Callbacks following the crawl, see CrawlHooks
+type CrawlObserver interface {
+	// a link (or a canonical or alternate address) was found on a page
+	OnDiscovered(from url.URL, address url.URL)
+	// the address was queued, it will be requested
+	OnEnqueued(entry FrontierEntry)
+	// the address won't be requested, reason is one of the Skip constants
+	OnSkipped(address url.URL, reason string)
+	// the page was downloaded (or found not modified, see HtmlPage.Previous)
+	OnFetched(page HtmlPage)
+	// the links and the metadata of the page were extracted, only for pages
+	// fetched successfully
+	OnParsed(page HtmlPageLinks)
+	// the page could not be fetched or parsed, no OnParsed follows
+	OnFailed(address url.URL, err error)
+}
+
+// Implements every callback doing nothing, to be embedded by observers
+// interested in a few events only
+type NopObserver struct{}
+
+func (NopObserver) OnDiscovered(from url.URL, address url.URL) {}
+func (NopObserver) OnEnqueued(entry FrontierEntry)             {}
+func (NopObserver) OnSkipped(address url.URL, reason string)   {}
+func (NopObserver) OnFetched(page HtmlPage)                    {}
+func (NopObserver) OnParsed(page HtmlPageLinks)                {}
+func (NopObserver) OnFailed(address url.URL, err error)        {}
+
+// Delivers the events of the pipeline to an observer. The fetchers, the
+// extractor and the mapper run in different goroutines, the hooks forward
+// their events to a single dedicated one: callbacks are never concurrent and
+// the events of a page come in the order they happened (fetched, parsed, then
+// its links discovered and enqueued or skipped). A slow observer slows the
+// crawl down. The methods do nothing on a nil *CrawlHooks
+type CrawlHooks struct {
+	events chan func(CrawlObserver)
+	done   chan bool
+}
+
+// Starts the goroutine calling the observer, Close must be called once the
+// crawl is over
+func StartCrawlHooks(observer CrawlObserver) *CrawlHooks {
+	hooks := &CrawlHooks{make(chan func(CrawlObserver), 1024), make(chan bool)}
+	go func() {
+		for event := range hooks.events {
+			event(observer)
+		}
+		close(hooks.done)
+	}()
+	return hooks
+}
+
+// Waits for the pending events to be delivered, no event must be sent afterwards
+func (hooks *CrawlHooks) Close() {
+	if hooks == nil {
+		return
+	}
+	close(hooks.events)
+	<-hooks.done
+}
+
+func (hooks *CrawlHooks) send(event func(CrawlObserver)) {
+	if hooks != nil {
+		hooks.events <- event
+	}
+}
+
+func (hooks *CrawlHooks) Discovered(from url.URL, address url.URL) {
+	hooks.send(func(observer CrawlObserver) { observer.OnDiscovered(from, address) })
+}
+
+func (hooks *CrawlHooks) Enqueued(entry FrontierEntry) {
+	hooks.send(func(observer CrawlObserver) { observer.OnEnqueued(entry) })
+}
+
+func (hooks *CrawlHooks) Skipped(address url.URL, reason string) {
+	hooks.send(func(observer CrawlObserver) { observer.OnSkipped(address, reason) })
+}
+
+func (hooks *CrawlHooks) Fetched(page HtmlPage) {
+	hooks.send(func(observer CrawlObserver) { observer.OnFetched(page) })
+}
+
+func (hooks *CrawlHooks) Parsed(page HtmlPageLinks) {
+	hooks.send(func(observer CrawlObserver) { observer.OnParsed(page) })
+}
+
+func (hooks *CrawlHooks) Failed(address url.URL, err error) {
+	hooks.send(func(observer CrawlObserver) { observer.OnFailed(address, err) })
+}
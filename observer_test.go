@@ -0,0 +1,131 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/url"
+)
+
+// Records the events as strings, ignoring the discovered addresses
+// (left to NopObserver)
+type RecordingObserver struct {
+	NopObserver
+	events []string
+}
+
+func (observer *RecordingObserver) OnEnqueued(entry FrontierEntry) {
+	observer.events = append(observer.events, "enqueued "+entry.Address.String())
+}
+
+func (observer *RecordingObserver) OnSkipped(address url.URL, reason string) {
+	observer.events = append(observer.events, "skipped "+address.String()+" "+reason)
+}
+
+func (observer *RecordingObserver) OnFetched(page HtmlPage) {
+	observer.events = append(observer.events, "fetched "+page.Address.String())
+}
+
+func (observer *RecordingObserver) OnParsed(page HtmlPageLinks) {
+	observer.events = append(observer.events, "parsed "+page.Address.String())
+}
+
+func (observer *RecordingObserver) OnFailed(address url.URL, err error) {
+	observer.events = append(observer.events, "failed "+address.String())
+}
+
+var _ = Describe("CrawlHooks", func() {
+
+	var (
+		root, _   = url.Parse("http://example.com/")
+		page, _   = url.Parse("http://example.com/a")
+		broken, _ = url.Parse("http://example.com/broken")
+	)
+
+	indexOf := func(events []string, event string) int {
+		for i, each := range events {
+			if each == event {
+				return i
+			}
+		}
+		Fail("missing event " + event)
+		return -1
+	}
+
+	It("should report the lifecycle of each address in order", func(done Done) {
+		okClient := &HttpClientMock{map[url.URL]string{
+			*root: "<a href=\"/a\"></a><a href=\"/broken\"></a><a href=\"http://other.com/\"></a>",
+			*page: "<a href=\"/\"></a>",
+		}}
+		client := &ComposedHttpClientMock{map[url.URL]HttpClient{
+			*root:   okClient,
+			*page:   okClient,
+			*broken: &BrokenHttpClientMock{},
+		}}
+
+		observer := &RecordingObserver{}
+		hooks := StartCrawlHooks(observer)
+
+		addressChan := make(chan url.URL)
+		pagesChan := StartHttpFetchersWithOptions(addressChan, client, FetcherOptions{Hooks: hooks})
+		linksChan := StartLinkExtractorWithOptions(pagesChan, ExtractorOptions{Hooks: hooks})
+		_, err := MapSiteWithOptions(*root, addressChan, linksChan, MapperOptions{Hooks: hooks})
+		Expect(err).To(BeNil())
+		hooks.Close()
+
+		events := observer.events
+		Expect(events).To(ConsistOf(
+			"enqueued http://example.com/",
+			"fetched http://example.com/",
+			"parsed http://example.com/",
+			"enqueued http://example.com/a",
+			"enqueued http://example.com/broken",
+			"skipped http://other.com/ out-of-scope",
+			"fetched http://example.com/a",
+			"parsed http://example.com/a",
+			"skipped http://example.com/ seen",
+			// never parsed
+			"failed http://example.com/broken",
+		))
+
+		Expect(indexOf(events, "fetched http://example.com/")).To(BeNumerically("<", indexOf(events, "parsed http://example.com/")))
+		Expect(indexOf(events, "parsed http://example.com/")).To(BeNumerically("<", indexOf(events, "enqueued http://example.com/a")))
+		Expect(indexOf(events, "enqueued http://example.com/a")).To(BeNumerically("<", indexOf(events, "fetched http://example.com/a")))
+		Expect(indexOf(events, "fetched http://example.com/a")).To(BeNumerically("<", indexOf(events, "parsed http://example.com/a")))
+		Expect(indexOf(events, "parsed http://example.com/a")).To(BeNumerically("<", indexOf(events, "skipped http://example.com/ seen")))
+
+		close(done)
+	})
+
+	// maps the single page retrieved from the root, without fetching anything
+	mapRoot := func(links []url.URL, options MapperOptions) []string {
+		observer := &RecordingObserver{}
+		options.Hooks = StartCrawlHooks(observer)
+		linksChan := make(chan HtmlPageLinks, 1)
+		linksChan <- HtmlPageLinks{*root, links, ResponseInfo{StatusCode: 200}, PageInfo{}}
+		close(linksChan)
+		MapSiteWithOptions(*root, make(chan url.URL, 10), linksChan, options)
+		options.Hooks.Close()
+		return observer.events
+	}
+
+	It("should report the addresses quarantined as traps", func() {
+		deep, _ := url.Parse("http://example.com/a/b/c")
+		events := mapRoot([]url.URL{*deep, *page}, MapperOptions{Traps: NewTrapDetector(TrapOptions{MaxPathDepth: 2})})
+
+		Expect(events).To(ContainElement("skipped http://example.com/a/b/c spider-trap"))
+		Expect(events).To(ContainElement("enqueued http://example.com/a"))
+	})
+
+	It("should report the addresses over the page limit", func() {
+		events := mapRoot([]url.URL{*page}, MapperOptions{MaxPages: 1})
+
+		Expect(events).To(ContainElement("skipped http://example.com/a limit"))
+	})
+
+	It("should do nothing when no hooks are configured", func() {
+		var hooks *CrawlHooks
+		hooks.Enqueued(FrontierEntry{*root, 0})
+		hooks.Close()
+	})
+})
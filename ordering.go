@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Decides the order in which the frontier hands out its entries: lower keys
+// are popped first, ties are broken by insertion order (seq grows on every push)
+type Ordering func(entry FrontierEntry, seq uint64) int64
+
+// Pops addresses in the order they were found
+func FifoOrdering(entry FrontierEntry, seq uint64) int64 {
+	return 0
+}
+
+// Breadth first: every page at depth n is popped before any page at depth n+1,
+// regardless of the order in which the fetchers completed
+func BfsOrdering(entry FrontierEntry, seq uint64) int64 {
+	return int64(entry.Depth)
+}
+
+// Depth first: the last address found is the first one popped
+func DfsOrdering(entry FrontierEntry, seq uint64) int64 {
+	return -int64(seq)
+}
+
+// Shallow URLs first (/about before /blog/2017/05/some-post)
+func FewestSegmentsOrdering(entry FrontierEntry, seq uint64) int64 {
+	segments := 0
+	for _, segment := range strings.Split(entry.Address.EscapedPath(), "/") {
+		if segment != "" {
+			segments++
+		}
+	}
+	return int64(segments)
+}
+
+// A regular expression matched against the whole URL and the score given to
+// the addresses it matches
+type ScoredPattern struct {
+	Pattern *regexp.Regexp
+	Score   int64
+}
+
+// Parses a "regexp=score" definition, e.g. "/products/=10"
+func ParseScoredPattern(definition string) (ScoredPattern, error) {
+	sep := strings.LastIndex(definition, "=")
+	if sep < 0 {
+		return ScoredPattern{}, fmt.Errorf("missing score in pattern %q", definition)
+	}
+	pattern, err := regexp.Compile(definition[:sep])
+	if err != nil {
+		return ScoredPattern{}, err
+	}
+	score, err := strconv.ParseInt(definition[sep+1:], 10, 64)
+	if err != nil {
+		return ScoredPattern{}, err
+	}
+	return ScoredPattern{pattern, score}, nil
+}
+
+// Highest score first, an address scores the sum of the patterns it matches
+// (addresses matching nothing score 0)
+func PatternOrdering(patterns []ScoredPattern) Ordering {
+	return func(entry FrontierEntry, seq uint64) int64 {
+		address := entry.Address.String()
+		score := int64(0)
+		for _, pattern := range patterns {
+			if pattern.Pattern.MatchString(address) {
+				score += pattern.Score
+			}
+		}
+		return -score
+	}
+}
+
+// Maps the names accepted on the command line to the orderings above
+func OrderingByName(name string, patterns []ScoredPattern) (Ordering, error) {
+	switch name {
+	case "", "fifo":
+		return FifoOrdering, nil
+	case "bfs":
+		return BfsOrdering, nil
+	case "dfs":
+		return DfsOrdering, nil
+	case "segments":
+		return FewestSegmentsOrdering, nil
+	case "pattern":
+		return PatternOrdering(patterns), nil
+	}
+	return nil, fmt.Errorf("unknown ordering %q", name)
+}
+
+// In flight cap applied when none is given (negative maxInFlight): without
+// one the frontier is emptied as soon as a page comes back, pages are then
+// requested in whatever order the fetchers complete and neither the ordering
+// nor the page limit can choose which pages make the cut. It applies to disk
+// stores as well: the frontier would otherwise be moved into memory, one
+// pending request per address
+const DefaultOrderedInFlight = 10
+
+// The in flight cap to use, 0 means no limit. withStore tells whether the
+// crawl state is kept in a disk store
+func EffectiveMaxInFlight(maxInFlight int, maxPages int, orderName string, withStore bool) int {
+	if maxInFlight >= 0 {
+		return maxInFlight
+	}
+	if withStore || maxPages > 0 || (orderName != "" && orderName != "fifo") {
+		return DefaultOrderedInFlight
+	}
+	return 0
+}
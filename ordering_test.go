@@ -0,0 +1,108 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/url"
+)
+
+var _ = Describe("Ordering", func() {
+
+	var (
+		pageUrl *url.URL
+		blogUrl *url.URL
+		postUrl *url.URL
+		productUrl *url.URL
+	)
+
+	// pushes the entries in the given order and returns the addresses in popping order
+	drain := func(ordering Ordering, entries ...FrontierEntry) []url.URL {
+		frontier := NewMemoryFrontier(ordering)
+		for _, entry := range entries {
+			frontier.Push(entry)
+		}
+		res := make([]url.URL, 0)
+		for {
+			entry, ok, _ := frontier.Pop()
+			if !ok {
+				return res
+			}
+			res = append(res, entry.Address)
+		}
+	}
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		blogUrl, _ = url.Parse("https://www.google.com/blog")
+		postUrl, _ = url.Parse("https://www.google.com/blog/2017/some-post")
+		productUrl, _ = url.Parse("https://www.google.com/products/phone")
+	})
+
+	It("should pop shallower pages first with bfs", func() {
+		res := drain(BfsOrdering,
+			FrontierEntry{*postUrl, 2},
+			FrontierEntry{*blogUrl, 1},
+			FrontierEntry{*pageUrl, 0},
+		)
+
+		Expect(res).To(Equal([]url.URL{*pageUrl, *blogUrl, *postUrl}))
+	})
+
+	It("should pop the last pushed page first with dfs", func() {
+		res := drain(DfsOrdering,
+			FrontierEntry{*pageUrl, 0},
+			FrontierEntry{*blogUrl, 1},
+			FrontierEntry{*postUrl, 2},
+		)
+
+		Expect(res).To(Equal([]url.URL{*postUrl, *blogUrl, *pageUrl}))
+	})
+
+	It("should pop pages with fewer path segments first", func() {
+		res := drain(FewestSegmentsOrdering,
+			FrontierEntry{*postUrl, 1},
+			FrontierEntry{*blogUrl, 1},
+			FrontierEntry{*pageUrl, 1},
+		)
+
+		Expect(res).To(Equal([]url.URL{*pageUrl, *blogUrl, *postUrl}))
+	})
+
+	It("should pop pages matching higher scoring patterns first", func() {
+		products, err := ParseScoredPattern("/products/=10")
+		Expect(err).NotTo(HaveOccurred())
+		blog, err := ParseScoredPattern("/blog=-5")
+		Expect(err).NotTo(HaveOccurred())
+
+		res := drain(PatternOrdering([]ScoredPattern{products, blog}),
+			FrontierEntry{*postUrl, 1},
+			FrontierEntry{*pageUrl, 1},
+			FrontierEntry{*productUrl, 1},
+		)
+
+		Expect(res).To(Equal([]url.URL{*productUrl, *pageUrl, *postUrl}))
+	})
+
+	It("should cap the pages in flight by default when the order matters", func() {
+		Expect(EffectiveMaxInFlight(-1, 0, "fifo", false)).To(Equal(0))
+		Expect(EffectiveMaxInFlight(-1, 500, "fifo", false)).To(Equal(DefaultOrderedInFlight))
+		Expect(EffectiveMaxInFlight(-1, 0, "bfs", false)).To(Equal(DefaultOrderedInFlight))
+		Expect(EffectiveMaxInFlight(0, 500, "bfs", false)).To(Equal(0))
+		Expect(EffectiveMaxInFlight(50, 0, "fifo", false)).To(Equal(50))
+	})
+
+	It("should cap the pages in flight by default when the state is on disk", func() {
+		Expect(EffectiveMaxInFlight(-1, 0, "fifo", true)).To(Equal(DefaultOrderedInFlight))
+		Expect(EffectiveMaxInFlight(-1, 0, "", true)).To(Equal(DefaultOrderedInFlight))
+		// explicit values win
+		Expect(EffectiveMaxInFlight(0, 0, "fifo", true)).To(Equal(0))
+		Expect(EffectiveMaxInFlight(50, 0, "fifo", true)).To(Equal(50))
+	})
+
+	It("should reject unknown orderings", func() {
+		_, err := OrderingByName("random", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+})
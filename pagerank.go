@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"net/url"
+)
+
+type PageRankOptions struct {
+	// probability of following a link rather than jumping to a random page
+	Damping float64
+	// iterations stop once the scores move less than this (sum of the absolute changes)
+	Tolerance     float64
+	MaxIterations int
+}
+
+func DefaultPageRankOptions() PageRankOptions {
+	return PageRankOptions{Damping: 0.85, Tolerance: 1e-6, MaxIterations: 100}
+}
+
+// Iterative PageRank over the internal links of the crawl, scores sum up to 1.
+// Pages without outbound internal links (dangling) spread their score over
+// every page, as if the visitor jumped somewhere at random
+func PageRank(pages PagesMap, options PageRankOptions) map[url.URL]float64 {
+	scores := make(map[url.URL]float64, len(pages))
+	if len(pages) == 0 {
+		return scores
+	}
+
+	links := internalLinks(pages)
+	n := float64(len(pages))
+	for address := range pages {
+		scores[address] = 1 / n
+	}
+
+	for iteration := 0; iteration < options.MaxIterations; iteration++ {
+		dangling := 0.0
+		for address, targets := range links {
+			if len(targets) == 0 {
+				dangling += scores[address]
+			}
+		}
+
+		// every page gets the random jump share plus its part of the dangling score
+		base := (1-options.Damping)/n + options.Damping*dangling/n
+		next := make(map[url.URL]float64, len(pages))
+		for address := range pages {
+			next[address] = base
+		}
+		for address, targets := range links {
+			if len(targets) == 0 {
+				continue
+			}
+			share := options.Damping * scores[address] / float64(len(targets))
+			for target := range targets {
+				next[target] += share
+			}
+		}
+
+		delta := 0.0
+		for address, score := range next {
+			delta += math.Abs(score - scores[address])
+		}
+		scores = next
+		if delta < options.Tolerance {
+			break
+		}
+	}
+
+	return scores
+}
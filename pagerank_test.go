@@ -0,0 +1,95 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"net/url"
+	"strings"
+)
+
+var _ = Describe("PageRank", func() {
+
+	var (
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+		otherPageUrl *url.URL
+		lastPageUrl *url.URL
+		pages PagesMap
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+		otherPageUrl, _ = url.Parse("https://www.google.com/other")
+		lastPageUrl, _ = url.Parse("https://www.google.com/42")
+
+		pages = PagesMap{
+			*pageUrl: {*aboutPageUrl, *otherPageUrl},
+			*aboutPageUrl: {*pageUrl},
+			*otherPageUrl: {*pageUrl, *lastPageUrl},
+			// dangling
+			*lastPageUrl: {},
+		}
+	})
+
+	It("should give the most linked page the highest score", func() {
+		scores := PageRank(pages, DefaultPageRankOptions())
+
+		total := 0.0
+		for _, score := range scores {
+			total += score
+		}
+		Expect(total).To(BeNumerically("~", 1, 1e-6))
+
+		Expect(scores[*pageUrl]).To(BeNumerically(">", scores[*aboutPageUrl]))
+		Expect(scores[*aboutPageUrl]).To(BeNumerically(">", scores[*lastPageUrl]))
+		Expect(scores[*aboutPageUrl]).To(BeNumerically("~", scores[*otherPageUrl], 1e-6))
+	})
+
+	It("should write the scores as sitemap priorities", func() {
+		store := make(MemoryPageStore)
+		store.Put(HtmlPageLinks{Address: *pageUrl, Response: ResponseInfo{StatusCode: 200, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"}})
+		store.Put(HtmlPageLinks{Address: *lastPageUrl, Response: ResponseInfo{StatusCode: 200}})
+		store.Put(HtmlPageLinks{Address: *aboutPageUrl, Response: ResponseInfo{StatusCode: 404}})
+
+		var out bytes.Buffer
+		err := WriteSitemapXml(&out, store, *pageUrl, map[url.URL]float64{*pageUrl: 0.5, *lastPageUrl: 0.05})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.String()).To(Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://www.google.com/</loc>
+    <lastmod>2006-01-02T15:04:05Z</lastmod>
+    <priority>1.0</priority>
+  </url>
+  <url>
+    <loc>https://www.google.com/42</loc>
+    <priority>0.2</priority>
+  </url>
+</urlset>
+`))
+	})
+
+	It("should leave out the pages that should not be indexed", func() {
+		otherHostUrl, _ := url.Parse("https://www.monzo.com/")
+		redirectUrl, _ := url.Parse("https://www.google.com/old")
+		noIndexUrl, _ := url.Parse("https://www.google.com/private")
+		duplicateUrl, _ := url.Parse("https://www.google.com/?sort=asc")
+		store := make(MemoryPageStore)
+		store.Put(HtmlPageLinks{Address: *pageUrl, Response: ResponseInfo{StatusCode: 200}, Info: PageInfo{Canonical: pageUrl.String()}})
+		store.Put(HtmlPageLinks{Address: *otherHostUrl, Response: ResponseInfo{StatusCode: 200}})
+		store.Put(HtmlPageLinks{Address: *redirectUrl, Response: ResponseInfo{StatusCode: 200, RedirectedTo: pageUrl.String()}})
+		store.Put(HtmlPageLinks{Address: *noIndexUrl, Response: ResponseInfo{StatusCode: 200}, Info: PageInfo{Robots: "NOINDEX, follow"}})
+		store.Put(HtmlPageLinks{Address: *duplicateUrl, Response: ResponseInfo{StatusCode: 200}, Info: PageInfo{Canonical: pageUrl.String()}})
+
+		var out bytes.Buffer
+		Expect(WriteSitemapXml(&out, store, *pageUrl, nil)).To(Succeed())
+
+		Expect(strings.Count(out.String(), "<loc>")).To(Equal(1))
+		Expect(out.String()).To(ContainSubstring("<loc>https://www.google.com/</loc>"))
+	})
+
+})
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Tracking and session parameters removed by default, a trailing * matches
+// any parameter starting with the rest of the name. Generic names (e.g. sid,
+// often a site, story or section id) are left out, they can be added with
+// -strip-param on the sites using them for sessions
+var DefaultStrippedParameters = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga", "_hsenc", "_hsmi",
+	"jsessionid", "phpsessid", "aspsessionid*", "cfid", "cftoken", "sessionid",
+}
+
+// Removes the configured parameters from the query string and from the path
+// parameters (e.g. /cart;jsessionid=42) of addresses, keeping count of what
+// it removed. Names are matched ignoring case
+type ParameterFilter struct {
+	exact    map[string]bool
+	prefixes []string
+	stripped map[string]int
+}
+
+func NewParameterFilter(names []string) *ParameterFilter {
+	filter := &ParameterFilter{exact: make(map[string]bool), stripped: make(map[string]int)}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if strings.HasSuffix(name, "*") {
+			filter.prefixes = append(filter.prefixes, strings.TrimSuffix(name, "*"))
+		} else if name != "" {
+			filter.exact[name] = true
+		}
+	}
+	return filter
+}
+
+func (filter *ParameterFilter) matches(name string) bool {
+	name = strings.ToLower(name)
+	if filter.exact[name] {
+		return true
+	}
+	for _, prefix := range filter.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// removes the matching name=value pairs from the raw query, the order of the
+// other ones is kept
+func (filter *ParameterFilter) cleanQuery(rawQuery string) string {
+	kept := make([]string, 0)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name := strings.SplitN(pair, "=", 2)[0]
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if filter.matches(name) {
+			filter.stripped[strings.ToLower(name)]++
+		} else {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+// removes the matching ;name=value parameters from the path segments
+func (filter *ParameterFilter) cleanPath(path string, count bool) string {
+	if !strings.Contains(path, ";") {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		parts := strings.Split(segment, ";")
+		kept := parts[:1]
+		for _, parameter := range parts[1:] {
+			name := strings.SplitN(parameter, "=", 2)[0]
+			if !filter.matches(name) {
+				kept = append(kept, parameter)
+			} else if count {
+				filter.stripped[strings.ToLower(name)]++
+			}
+		}
+		segments[i] = strings.Join(kept, ";")
+	}
+	return strings.Join(segments, "/")
+}
+
+// The address without the filtered parameters
+func (filter *ParameterFilter) Clean(address url.URL) url.URL {
+	address.Path = filter.cleanPath(address.Path, true)
+	if address.RawPath != "" {
+		address.RawPath = filter.cleanPath(address.RawPath, false)
+	}
+	if address.RawQuery != "" {
+		address.RawQuery = filter.cleanQuery(address.RawQuery)
+	}
+	address.ForceQuery = false
+	return address
+}
+
+// Cleans every link, links that become the same address are kept once
+func (filter *ParameterFilter) CleanLinks(links []url.URL) []url.URL {
+	cleaned := make([]url.URL, 0, len(links))
+	found := make(map[url.URL]bool, len(links))
+	for _, link := range links {
+		link = filter.Clean(link)
+		if !found[link] {
+			found[link] = true
+			cleaned = append(cleaned, link)
+		}
+	}
+	return cleaned
+}
+
+type StrippedParameter struct {
+	Name string `json:"name"`
+	// number of links it was removed from
+	Count int `json:"count"`
+}
+
+// The parameters removed so far, the most frequent first
+func (filter *ParameterFilter) Report() ParameterReport {
+	parameters := make([]StrippedParameter, 0, len(filter.stripped))
+	for name, count := range filter.stripped {
+		parameters = append(parameters, StrippedParameter{name, count})
+	}
+	sort.Slice(parameters, func(i, j int) bool {
+		if parameters[i].Count != parameters[j].Count {
+			return parameters[i].Count > parameters[j].Count
+		}
+		return parameters[i].Name < parameters[j].Name
+	})
+	return ParameterReport{parameters}
+}
+
+type ParameterReport struct {
+	Parameters []StrippedParameter `json:"parameters"`
+}
+
+func (report ParameterReport) IsEmpty() bool {
+	return len(report.Parameters) == 0
+}
+
+func (report ParameterReport) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (report ParameterReport) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Stripped parameters (%d)\n", len(report.Parameters))
+	for _, parameter := range report.Parameters {
+		fmt.Fprintf(w, "  %-20s %d links\n", parameter.Name, parameter.Count)
+	}
+}
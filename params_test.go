@@ -0,0 +1,70 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"net/url"
+)
+
+var _ = Describe("ParameterFilter", func() {
+
+	var (
+		filter *ParameterFilter
+	)
+
+	clean := func(raw string) string {
+		address, _ := url.Parse(raw)
+		cleaned := filter.Clean(*address)
+		return cleaned.String()
+	}
+
+	BeforeEach(func() {
+		filter = NewParameterFilter(DefaultStrippedParameters)
+	})
+
+	It("should remove tracking parameters keeping the other ones in order", func() {
+		Expect(clean("https://www.google.com/?utm_source=mail&q=go&UTM_Medium=x&fbclid=42&page=2")).
+			To(Equal("https://www.google.com/?q=go&page=2"))
+		Expect(clean("https://www.google.com/about?utm_source=mail")).To(Equal("https://www.google.com/about"))
+	})
+
+	It("should remove session path parameters", func() {
+		Expect(clean("https://www.google.com/cart;jsessionid=A1B2?PHPSESSID=42&item=7")).
+			To(Equal("https://www.google.com/cart?item=7"))
+		Expect(clean("https://www.google.com/matrix;color=red/")).To(Equal("https://www.google.com/matrix;color=red/"))
+	})
+
+	It("should leave other addresses unchanged", func() {
+		Expect(clean("https://www.google.com/search?q=utm_source#top")).To(Equal("https://www.google.com/search?q=utm_source#top"))
+		// generic names may well identify the content
+		Expect(clean("https://www.google.com/story?sid=42")).To(Equal("https://www.google.com/story?sid=42"))
+	})
+
+	It("should keep once the links that become the same address", func() {
+		first, _ := url.Parse("https://www.google.com/?utm_source=a")
+		second, _ := url.Parse("https://www.google.com/?utm_source=b")
+		other, _ := url.Parse("https://www.google.com/about")
+		root, _ := url.Parse("https://www.google.com/")
+
+		Expect(filter.CleanLinks([]url.URL{*first, *other, *second})).To(Equal([]url.URL{*root, *other}))
+	})
+
+	It("should report the stripped parameters", func() {
+		clean("https://www.google.com/?utm_source=a&utm_medium=b")
+		clean("https://www.google.com/about?utm_source=c")
+		clean("https://www.google.com/cart;jsessionid=42")
+
+		Expect(filter.Report().Parameters).To(Equal([]StrippedParameter{
+			{"utm_source", 2},
+			{"jsessionid", 1},
+			{"utm_medium", 1},
+		}))
+
+		var out bytes.Buffer
+		filter.Report().WriteText(&out)
+		Expect(out.String()).To(ContainSubstring("Stripped parameters (3)"))
+	})
+
+})
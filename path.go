@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Returns up to limit shortest link paths (each one starting with from and
+// ending with to), none if to can't be reached from from
+func ShortestPaths(pages PagesMap, from url.URL, to url.URL, limit int) [][]url.URL {
+	if from == to {
+		return [][]url.URL{{from}}
+	}
+
+	// breadth first visit remembering, for each page, every page reaching it
+	// along a shortest path
+	distance := map[url.URL]int{from: 0}
+	parents := make(map[url.URL][]url.URL)
+	queue := []url.URL{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if reached, found := distance[to]; found && distance[current] >= reached {
+			// every shortest path has been found already
+			break
+		}
+		for _, next := range pages[current] {
+			nextDistance, visited := distance[next]
+			if !visited {
+				distance[next] = distance[current] + 1
+				parents[next] = []url.URL{current}
+				queue = append(queue, next)
+			} else if nextDistance == distance[current]+1 {
+				parents[next] = append(parents[next], current)
+			}
+		}
+	}
+
+	paths := make([][]url.URL, 0)
+	if _, found := distance[to]; !found {
+		return paths
+	}
+
+	// walk the parents back from the target, paths are built reversed
+	var walk func(current url.URL, suffix []url.URL)
+	walk = func(current url.URL, suffix []url.URL) {
+		if len(paths) >= limit {
+			return
+		}
+		suffix = append([]url.URL{current}, suffix...)
+		if current == from {
+			paths = append(paths, suffix)
+			return
+		}
+		for _, parent := range parents[current] {
+			walk(parent, suffix)
+		}
+	}
+	walk(to, nil)
+
+	return paths
+}
+
+func WritePaths(w io.Writer, from url.URL, to url.URL, paths [][]url.URL) {
+	if len(paths) == 0 {
+		fmt.Fprintln(w, to.String(), "is not reachable from", from.String())
+		return
+	}
+	for i, path := range paths {
+		fmt.Fprintf(w, "Path %d (%d clicks)\n", i+1, len(path)-1)
+		for level, address := range path {
+			for j := 0; j < level; j++ {
+				fmt.Fprint(w, "  ")
+			}
+			if level > 0 {
+				fmt.Fprint(w, "|-")
+			}
+			fmt.Fprintln(w, address.String())
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"net/url"
+)
+
+var _ = Describe("ShortestPaths", func() {
+
+	var (
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+		otherPageUrl *url.URL
+		lastPageUrl *url.URL
+		islandUrl *url.URL
+		pages PagesMap
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+		otherPageUrl, _ = url.Parse("https://www.google.com/other")
+		lastPageUrl, _ = url.Parse("https://www.google.com/42")
+		islandUrl, _ = url.Parse("https://www.google.com/island")
+
+		pages = PagesMap{
+			*pageUrl: {*aboutPageUrl, *otherPageUrl},
+			*aboutPageUrl: {*pageUrl, *lastPageUrl},
+			*otherPageUrl: {*lastPageUrl},
+			*lastPageUrl: {*pageUrl},
+			*islandUrl: {*pageUrl},
+		}
+	})
+
+	It("should find every shortest path", func() {
+		paths := ShortestPaths(pages, *pageUrl, *lastPageUrl, 10)
+
+		Expect(paths).To(ConsistOf(
+			[]url.URL{*pageUrl, *aboutPageUrl, *lastPageUrl},
+			[]url.URL{*pageUrl, *otherPageUrl, *lastPageUrl},
+		))
+	})
+
+	It("should stop at the given number of paths", func() {
+		Expect(ShortestPaths(pages, *pageUrl, *lastPageUrl, 1)).To(HaveLen(1))
+	})
+
+	It("should report unreachable pages", func() {
+		paths := ShortestPaths(pages, *pageUrl, *islandUrl, 10)
+		Expect(paths).To(BeEmpty())
+
+		var out bytes.Buffer
+		WritePaths(&out, *pageUrl, *islandUrl, paths)
+		Expect(out.String()).To(Equal("https://www.google.com/island is not reachable from https://www.google.com/\n"))
+	})
+
+})
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// True if the file is a terminal, where the progress can be updated in place
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// One line summary of the crawl so far. The ETA assumes the addresses known
+// now are the last ones, it grows as new links are found
+func ProgressLine(snapshot MetricsSnapshot, elapsed time.Duration) string {
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(snapshot.Fetched) / elapsed.Seconds()
+	}
+	eta := "-"
+	if remaining := snapshot.Pending + snapshot.InFlight; rate > 0 {
+		eta = time.Duration(float64(remaining) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+	return fmt.Sprintf("%d fetched, %d pending, %d in flight, %d errors, %.1f pages/s, elapsed %s, ETA %s",
+		snapshot.Fetched, snapshot.Pending, snapshot.InFlight, snapshot.Errors, rate,
+		elapsed.Round(time.Second), eta)
+}
+
+// Prints the progress of the crawl every interval, see StartProgress
+type Progress struct {
+	w       io.Writer
+	metrics *Metrics
+	inPlace bool
+	start   time.Time
+	stop    chan bool
+	done    chan bool
+}
+
+// Starts printing the progress read from the metrics. In place the line is
+// rewritten at every update (meant for terminals), otherwise a new line is
+// printed each time (meant for log files)
+func StartProgress(w io.Writer, metrics *Metrics, inPlace bool, interval time.Duration) *Progress {
+	progress := &Progress{w, metrics, inPlace, time.Now(), make(chan bool), make(chan bool)}
+
+	go func() {
+		defer close(progress.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				progress.print()
+			case <-progress.stop:
+				progress.print()
+				if progress.inPlace {
+					fmt.Fprintln(progress.w)
+				}
+				return
+			}
+		}
+	}()
+
+	return progress
+}
+
+func (progress *Progress) print() {
+	line := ProgressLine(progress.metrics.Snapshot(), time.Since(progress.start))
+	if progress.inPlace {
+		// back to the start of the line and clear it
+		fmt.Fprint(progress.w, "\r\033[K"+line)
+	} else {
+		fmt.Fprintln(progress.w, line)
+	}
+}
+
+// Prints the final progress and stops the updates
+func (progress *Progress) Stop() {
+	close(progress.stop)
+	<-progress.done
+}
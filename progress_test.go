@@ -0,0 +1,70 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bytes.Buffer shared between the progress goroutine and the test
+type syncBuffer struct {
+	mutex sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+var _ = Describe("Progress", func() {
+
+	It("should summarise the crawl with rate and ETA", func() {
+		line := ProgressLine(MetricsSnapshot{Fetched: 100, Pending: 40, InFlight: 10, Errors: 2}, 20*time.Second)
+
+		Expect(line).To(Equal("100 fetched, 40 pending, 10 in flight, 2 errors, 5.0 pages/s, elapsed 20s, ETA 10s"))
+	})
+
+	It("should not guess an ETA before the first page", func() {
+		line := ProgressLine(MetricsSnapshot{Pending: 1}, 0)
+
+		Expect(line).To(HaveSuffix("ETA -"))
+	})
+
+	It("should print a line per update when not in place", func() {
+		metrics := NewMetrics()
+		metrics.ObserveFetch(200, 10, time.Millisecond)
+		out := &syncBuffer{}
+
+		progress := StartProgress(out, metrics, false, 10*time.Millisecond)
+		Eventually(func() int { return strings.Count(out.String(), "\n") }).Should(BeNumerically(">=", 2))
+		progress.Stop()
+
+		Expect(out.String()).NotTo(ContainSubstring("\r"))
+		Expect(out.String()).To(HavePrefix("1 fetched, 0 pending"))
+	})
+
+	It("should rewrite the line when in place", func() {
+		metrics := NewMetrics()
+		out := &syncBuffer{}
+
+		progress := StartProgress(out, metrics, true, time.Hour)
+		metrics.ObserveFetch(200, 10, time.Millisecond)
+		progress.Stop()
+
+		Expect(out.String()).To(HavePrefix("\r\033[K1 fetched"))
+		Expect(out.String()).To(HaveSuffix("\n"))
+	})
+
+})
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Reads a list of addresses, one per line, blank lines and lines starting
+// with # are ignored
+func ParseSeeds(reader io.Reader) ([]url.URL, error) {
+	seeds := make([]url.URL, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		seed, err := url.Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, *seed)
+	}
+	return seeds, scanner.Err()
+}
+
+func ReadSeedsFile(path string) ([]url.URL, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseSeeds(file)
+}
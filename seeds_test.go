@@ -0,0 +1,28 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/url"
+	"strings"
+)
+
+var _ = Describe("ParseSeeds", func() {
+
+	It("should read one address per line skipping blanks and comments", func() {
+		pageUrl, _ := url.Parse("https://www.google.com/")
+		monzoUrl, _ := url.Parse("https://www.monzo.com/")
+
+		seeds, err := ParseSeeds(strings.NewReader(`
+			# sections not linked from the home page
+			https://www.google.com/
+
+			https://www.monzo.com/
+		`))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(seeds).To(Equal([]url.URL{*pageUrl, *monzoUrl}))
+	})
+
+})
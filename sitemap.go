@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Both <urlset> and <sitemapindex> documents, only the locations are of interest
+type sitemapXml struct {
+	XMLName  xml.Name
+	Urls     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+func fetchBody(client HttpClient, address url.URL) ([]byte, error) {
+	resp, err := client.Get(address)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %d", address.String(), resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Sitemaps may be gzipped (sitemap.xml.gz), we look at the magic number rather
+// than trusting extensions and headers
+func gunzipIfNeeded(body []byte) ([]byte, error) {
+	if len(body) < 2 || body[0] != 0x1f || body[1] != 0x8b {
+		return body, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// Returns the addresses listed by the page locations and by the nested sitemaps
+// of an index
+func parseSitemap(body []byte) ([]string, []string, error) {
+	var parsed sitemapXml
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		return nil, nil, err
+	}
+
+	pages := make([]string, 0, len(parsed.Urls))
+	for _, loc := range parsed.Urls {
+		pages = append(pages, strings.TrimSpace(loc.Loc))
+	}
+	sitemaps := make([]string, 0, len(parsed.Sitemaps))
+	for _, loc := range parsed.Sitemaps {
+		sitemaps = append(sitemaps, strings.TrimSpace(loc.Loc))
+	}
+	return pages, sitemaps, nil
+}
+
+// Extracts the "Sitemap:" lines of a robots.txt
+func parseRobotsSitemaps(body io.Reader, base url.URL) []url.URL {
+	sitemaps := make([]url.URL, 0)
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		sep := strings.Index(line, ":")
+		if sep < 0 || !strings.EqualFold(line[:sep], "sitemap") {
+			continue
+		}
+		location, err := url.Parse(strings.TrimSpace(line[sep+1:]))
+		if err != nil {
+			log.Warn("Can't parse sitemap address in robots.txt ", line, err)
+			continue
+		}
+		sitemaps = append(sitemaps, *base.ResolveReference(location))
+	}
+	return sitemaps
+}
+
+// The sitemaps published for the root's host: the ones declared in robots.txt
+// or, when there are none, the conventional /sitemap.xml
+func DiscoverSitemaps(client HttpClient, root url.URL) []url.URL {
+	robots := url.URL{Scheme: root.Scheme, Host: root.Host, Path: "/robots.txt"}
+	body, err := fetchBody(client, robots)
+	if err != nil {
+		log.Info("No robots.txt for ", root.Host, " ", err)
+	} else if sitemaps := parseRobotsSitemaps(bytes.NewReader(body), robots); len(sitemaps) > 0 {
+		return sitemaps
+	}
+	return []url.URL{{Scheme: root.Scheme, Host: root.Host, Path: "/sitemap.xml"}}
+}
+
+// Fetches the given sitemaps, following sitemap indexes, and returns every page
+// address they list (without duplicates). Sitemaps that can't be fetched or
+// parsed are logged and skipped, an error is returned when none could be read
+func FetchSitemapUrls(client HttpClient, sitemaps []url.URL) ([]url.URL, error) {
+	read := 0
+	visited := make(map[url.URL]bool)
+	listed := make(map[url.URL]bool)
+	pages := make([]url.URL, 0)
+
+	queue := append([]url.URL{}, sitemaps...)
+	for len(queue) > 0 {
+		sitemap := queue[0]
+		queue = queue[1:]
+		if visited[sitemap] {
+			continue
+		}
+		visited[sitemap] = true
+
+		log.Debug("Fetching sitemap ", sitemap)
+		body, err := fetchBody(client, sitemap)
+		if err == nil {
+			body, err = gunzipIfNeeded(body)
+		}
+		var locations, nested []string
+		if err == nil {
+			locations, nested, err = parseSitemap(body)
+		}
+		if err != nil {
+			log.Error("Can't read sitemap ", sitemap.String(), " ", err)
+			continue
+		}
+		read++
+
+		for _, raw := range nested {
+			if nestedUrl, err := url.Parse(raw); err == nil {
+				queue = append(queue, *sitemap.ResolveReference(nestedUrl))
+			} else {
+				log.Warn("Can't parse sitemap address ", raw, err)
+			}
+		}
+		for _, raw := range locations {
+			page, err := url.Parse(raw)
+			if err != nil {
+				log.Warn("Can't parse sitemap address ", raw, err)
+				continue
+			}
+			if !listed[*page] {
+				listed[*page] = true
+				pages = append(pages, *page)
+			}
+		}
+	}
+
+	if read == 0 && len(sitemaps) > 0 {
+		return nil, fmt.Errorf("no sitemap could be read, starting from %s", sitemaps[0].String())
+	}
+	return pages, nil
+}
+
+// How the crawled pages relate to the ones published in the sitemaps
+type SitemapComparison struct {
+	// listed in the sitemaps but not reachable following links
+	Orphans []string `json:"orphans"`
+	// reachable following links, worth indexing (see belongsInSitemap) but not
+	// listed in the sitemaps
+	Missing []string `json:"missing"`
+	// listed in the sitemaps, reachable, but answering with an error
+	Broken []string `json:"broken"`
+}
+
+// Compares the crawled pages with the ones listed in the sitemaps, only the pages
+// of the root's host that belong in a sitemap can be missing from it
+func CompareWithSitemap(store PageStore, listed []url.URL, root url.URL) (SitemapComparison, error) {
+	comparison := SitemapComparison{make([]string, 0), make([]string, 0), make([]string, 0)}
+
+	inSitemap := make(map[string]bool, len(listed))
+	for _, address := range listed {
+		inSitemap[address.String()] = true
+	}
+
+	crawled := make(map[string]bool, store.Len())
+	err := store.Each(func(page HtmlPageLinks) error {
+		if page.Response.StatusOnly {
+			// not reachable following links
+			return nil
+		}
+		address := page.Address.String()
+		crawled[address] = true
+		broken := isBrokenStatus(page.Response.StatusCode)
+		if inSitemap[address] && broken {
+			comparison.Broken = append(comparison.Broken, address)
+		} else if !inSitemap[address] && belongsInSitemap(page, root) {
+			comparison.Missing = append(comparison.Missing, address)
+		}
+		return nil
+	})
+	if err != nil {
+		return comparison, err
+	}
+
+	for address := range inSitemap {
+		if !crawled[address] {
+			comparison.Orphans = append(comparison.Orphans, address)
+		}
+	}
+
+	sort.Strings(comparison.Orphans)
+	sort.Strings(comparison.Missing)
+	sort.Strings(comparison.Broken)
+	return comparison, nil
+}
+
+func (comparison SitemapComparison) WriteText(w io.Writer) {
+	sections := []struct {
+		title     string
+		addresses []string
+	}{
+		{"Orphans (in the sitemap, not reachable by links)", comparison.Orphans},
+		{"Missing from the sitemap", comparison.Missing},
+		{"Broken pages in the sitemap", comparison.Broken},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, "%s (%d)\n", section.title, len(section.addresses))
+		for _, address := range section.addresses {
+			fmt.Fprintln(w, "  ", address)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"compress/gzip"
+	"net/url"
+)
+
+var _ = Describe("Sitemap", func() {
+
+	var (
+		pageUrl *url.URL
+		aboutPageUrl *url.URL
+		otherPageUrl *url.URL
+		robotsUrl *url.URL
+		indexUrl *url.URL
+		gzippedUrl *url.URL
+		client HttpClientMock
+	)
+
+	gzipped := func(content string) string {
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		writer.Write([]byte(content))
+		writer.Close()
+		return buffer.String()
+	}
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		aboutPageUrl, _ = url.Parse("https://www.google.com/about")
+		otherPageUrl, _ = url.Parse("https://www.google.com/other")
+		robotsUrl, _ = url.Parse("https://www.google.com/robots.txt")
+		indexUrl, _ = url.Parse("https://www.google.com/sitemap_index.xml")
+		gzippedUrl, _ = url.Parse("https://www.google.com/sitemap-pages.xml.gz")
+
+		client = HttpClientMock{
+			map[url.URL]string{
+				*robotsUrl: "User-agent: *\nDisallow: /private\nSitemap: https://www.google.com/sitemap_index.xml\n",
+				*indexUrl: `<?xml version="1.0" encoding="UTF-8"?>
+					<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+						<sitemap><loc>https://www.google.com/sitemap-pages.xml.gz</loc></sitemap>
+					</sitemapindex>`,
+				*gzippedUrl: gzipped(`<?xml version="1.0" encoding="UTF-8"?>
+					<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+						<url><loc>https://www.google.com/</loc></url>
+						<url><loc> https://www.google.com/other </loc></url>
+					</urlset>`),
+			},
+		}
+	})
+
+	It("should find the sitemaps listed in robots.txt", func() {
+		Expect(DiscoverSitemaps(&client, *pageUrl)).To(Equal([]url.URL{*indexUrl}))
+	})
+
+	It("should follow indexes and read gzipped sitemaps", func() {
+		listed, err := FetchSitemapUrls(&client, []url.URL{*indexUrl})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(listed).To(Equal([]url.URL{*pageUrl, *otherPageUrl}))
+	})
+
+	It("should fail when no sitemap can be read", func() {
+		missing, _ := url.Parse("https://www.google.com/missing-sitemap.xml")
+		_, err := FetchSitemapUrls(&BrokenHttpClientMock{}, []url.URL{*missing})
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should report orphans and pages missing from the sitemap", func() {
+		pages := make(MemoryPageStore)
+		pages.Put(HtmlPageLinks{Address: *pageUrl, LinksTo: []url.URL{*aboutPageUrl}, Response: ResponseInfo{StatusCode: 200}})
+		pages.Put(HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{}, Response: ResponseInfo{StatusCode: 200}})
+
+		comparison, err := CompareWithSitemap(pages, []url.URL{*pageUrl, *otherPageUrl}, *pageUrl)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(comparison.Orphans).To(Equal([]string{otherPageUrl.String()}))
+		Expect(comparison.Missing).To(Equal([]string{aboutPageUrl.String()}))
+		Expect(comparison.Broken).To(BeEmpty())
+	})
+
+	It("should only report the missing pages worth indexing", func() {
+		redirectedUrl, _ := url.Parse("https://www.google.com/old-about")
+		noIndexUrl, _ := url.Parse("https://www.google.com/search")
+		pages := make(MemoryPageStore)
+		pages.Put(HtmlPageLinks{Address: *pageUrl, LinksTo: []url.URL{}, Response: ResponseInfo{StatusCode: 200}})
+		pages.Put(HtmlPageLinks{Address: *aboutPageUrl, LinksTo: []url.URL{}, Response: ResponseInfo{StatusCode: 200}})
+		pages.Put(HtmlPageLinks{
+			Address: *redirectedUrl,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 200, RedirectedTo: aboutPageUrl.String()},
+		})
+		pages.Put(HtmlPageLinks{
+			Address: *noIndexUrl,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 200},
+			Info: PageInfo{Robots: "noindex, follow"},
+		})
+
+		comparison, err := CompareWithSitemap(pages, []url.URL{*pageUrl}, *pageUrl)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(comparison.Missing).To(Equal([]string{aboutPageUrl.String()}))
+	})
+
+})
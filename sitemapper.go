@@ -1,34 +1,392 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"net/http"
 	"net/url"
-	log "github.com/sirupsen/logrus"
 	"os"
+	"strings"
+	"time"
+	log "github.com/sirupsen/logrus"
 )
 
+// flag.Value collecting every occurrence of a repeatable flag
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
 func main() {
 
-	root, err := url.Parse(os.Args[1])
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
+	storePath := flag.String("store", "", "keep the crawl state in this bolt file instead of memory (for very large sites)")
+	bloomSize := flag.Int("bloom", 0, "put a Bloom filter sized for this many pages in front of the seen set")
+	maxInFlight := flag.Int("max-in-flight", -1, "maximum number of pages fetched at once, 0 means no limit (by default 10 with -store, -max-pages or an -order other than fifo, no limit otherwise)")
+	maxPages := flag.Int("max-pages", 0, "stop requesting pages after this many (0 means no limit)")
+	orderName := flag.String("order", "fifo", "crawling order: fifo, bfs, dfs, segments (fewest path segments first) or pattern")
+	xmlPath := flag.String("xml", "", "write a sitemap.xml of the crawled pages to this file, priorities come from their PageRank")
+	savePath := flag.String("save", "", "save the crawl result (links, status codes, validators) as json to this file")
+	previousPath := flag.String("previous", "", "crawl result saved by a previous run, unchanged pages are not downloaded again")
+	cacheDir := flag.String("cache", "", "keep downloaded pages in this directory and reuse them on later runs (see the cache command)")
+	cacheTtl := flag.Duration("cache-ttl", 0, "download again the cached pages older than this (0 means never)")
+	offline := flag.Bool("offline", false, "only use the cached pages, never hit the network")
+	compareSitemap := flag.Bool("compare-sitemap", false, "compare the crawled pages with the ones listed in the published sitemaps")
+	var sitemaps stringList
+	flag.Var(&sitemaps, "sitemap", "sitemap (or sitemap index) to compare with, by default the ones listed in robots.txt or /sitemap.xml (repeatable)")
+	seedsPath := flag.String("seeds", "", "file listing additional addresses to start from, one per line")
+	var seedSitemaps stringList
+	flag.Var(&seedSitemaps, "seed-sitemap", "sitemap (or sitemap index) whose pages are used as additional starting addresses (repeatable)")
+	detectTraps := flag.Bool("traps", true, "quarantine the addresses that look like spider traps (calendars, faceted search, recursive links)")
+	trapDefaults := DefaultTrapOptions()
+	trapUrlLength := flag.Int("trap-url-length", trapDefaults.MaxUrlLength, "addresses longer than this are quarantined")
+	trapDepth := flag.Int("trap-depth", trapDefaults.MaxPathDepth, "addresses with more path segments than this are quarantined")
+	trapRepeats := flag.Int("trap-repeats", trapDefaults.MaxSegmentRepeats, "addresses repeating a path segment more than this many times are quarantined")
+	trapQueries := flag.Int("trap-queries", trapDefaults.MaxQueryVariants, "distinct query strings followed for the same path, the others are quarantined")
+	stripParams := flag.Bool("strip-params", true, "remove tracking and session parameters (utm_*, fbclid, jsessionid, ...) from links")
+	var strippedParams stringList
+	flag.Var(&strippedParams, "strip-param", "additional parameter to remove from links, a trailing * matches any suffix (repeatable)")
+	detectSoft404s := flag.Bool("soft-404", false, "probe each host with a missing page and flag the pages that look like it (error pages answering 200)")
+	userAgent := flag.String("user-agent", "", "User-Agent sent with every request, Go's default if empty")
+	var headers stringList
+	flag.Var(&headers, "header", "\"Name: value\" header sent with every request (repeatable)")
+	cookies := flag.Bool("cookies", false, "keep the cookies set by the sites and send them back, as a browser would")
+	cookiesFile := flag.String("cookies-file", "", "Netscape cookies.txt file whose cookies are sent with the requests, implies -cookies")
+	var basicAuth stringList
+	flag.Var(&basicAuth, "auth", "host=user:password basic credentials, only sent to that host (repeatable)")
+	var bearerAuth stringList
+	flag.Var(&bearerAuth, "bearer", "host=token bearer credentials, only sent to that host (repeatable)")
+	loginUrl := flag.String("login-url", "", "post the -login-field values to this form before crawling and keep the session cookies, implies -cookies")
+	var loginFields stringList
+	flag.Var(&loginFields, "login-field", "name=value field posted to -login-url (repeatable)")
+	proxy := flag.String("proxy", "", "http://, https:// or socks5:// proxy, by default the one of HTTP_PROXY and HTTPS_PROXY")
+	caFile := flag.String("ca-file", "", "PEM bundle of certificate authorities trusted along with the system ones")
+	certFile := flag.String("cert", "", "PEM client certificate, for servers requiring mutual TLS (with -key)")
+	keyFile := flag.String("key", "", "PEM key of the -cert client certificate")
+	insecure := flag.Bool("insecure-skip-verify", false, "accept any server certificate (self signed test servers only)")
+	metricsAddress := flag.String("metrics", "", "serve Prometheus metrics on this address (e.g. :9090) at /metrics while crawling")
+	showProgress := flag.Bool("progress", true, "show pages fetched, pending, errors, rate and ETA on stderr while crawling")
+	progressInterval := flag.Duration("progress-interval", 10*time.Second, "how often the progress is printed when stderr is not a terminal")
+	logLevel := flag.String("log-level", "", "debug, info, warn or error, by default warn while the progress is shown on a terminal and info otherwise")
+	var priorities stringList
+	flag.Var(&priorities, "priority", "regexp=score, pages matching higher scores are crawled first with -order pattern (repeatable)")
+	flag.Parse()
+
+	// on a terminal the progress is updated in place, log lines would break it
+	progressInPlace := *showProgress && IsTerminal(os.Stderr)
+	if *logLevel == "" {
+		*logLevel = "info"
+		if progressInPlace {
+			*logLevel = "warn"
+		}
+	}
+	level, err := log.ParseLevel(*logLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.SetLevel(level)
+
+	// every address we start from, the first one is the root
+	seeds := make([]url.URL, 0, flag.NArg())
+	for _, arg := range flag.Args() {
+		seed, err := url.Parse(arg)
+		if err != nil {
+			log.Fatal("Can't parse root")
+			panic(1)
+		}
+		seeds = append(seeds, *seed)
+	}
+
+	if *seedsPath != "" {
+		fromFile, err := ReadSeedsFile(*seedsPath)
+		if err != nil {
+			log.Fatal("Can't read seeds ", *seedsPath, " ", err)
+		}
+		seeds = append(seeds, fromFile...)
+	}
+
+	patterns := make([]ScoredPattern, 0, len(priorities))
+	for _, definition := range priorities {
+		pattern, err := ParseScoredPattern(definition)
+		if err != nil {
+			log.Fatal("Can't parse priority ", err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	ordering, err := OrderingByName(*orderName, patterns)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := MapperOptions{
+		Frontier:    NewMemoryFrontier(ordering),
+		MaxInFlight: EffectiveMaxInFlight(*maxInFlight, *maxPages, *orderName, *storePath != ""),
+		MaxPages:    *maxPages,
+	}
+
+	if *detectTraps {
+		options.Traps = NewTrapDetector(TrapOptions{
+			MaxUrlLength:      *trapUrlLength,
+			MaxPathDepth:      *trapDepth,
+			MaxSegmentRepeats: *trapRepeats,
+			MaxQueryVariants:  *trapQueries,
+		})
+	}
+
+	if *stripParams || len(strippedParams) > 0 {
+		names := append([]string{}, strippedParams...)
+		if *stripParams {
+			names = append(names, DefaultStrippedParameters...)
+		}
+		options.Parameters = NewParameterFilter(names)
+	}
+
+	if *storePath != "" {
+		store, err := OpenDiskStore(*storePath)
+		if err != nil {
+			log.Fatal("Can't open store ", *storePath, " ", err)
+		}
+		defer store.Close()
+
+		options.Frontier = store.Frontier(ordering)
+		options.Seen = store.Seen()
+		options.Pages = store.Pages()
+	}
+
+	if *bloomSize > 0 {
+		// without a disk store the filter replaces the in memory set, trading
+		// a few skipped pages for a much smaller footprint
+		options.Seen = NewBloomSeenSet(NewBloomFilter(*bloomSize, 0.001), options.Seen)
+	}
+
+	header, err := ParseHeaders(headers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	credentials := make([]HostCredentials, 0, len(basicAuth)+len(bearerAuth))
+	for _, definition := range basicAuth {
+		parsed, err := ParseBasicCredentials(definition)
+		if err != nil {
+			log.Fatal(err)
+		}
+		credentials = append(credentials, parsed)
+	}
+	for _, definition := range bearerAuth {
+		parsed, err := ParseBearerCredentials(definition)
+		if err != nil {
+			log.Fatal(err)
+		}
+		credentials = append(credentials, parsed)
+	}
+	defaultClient, err := NewDefaultHttpClient(HttpClientOptions{
+		UserAgent:          *userAgent,
+		Header:             header,
+		Cookies:            *cookies || *loginUrl != "",
+		CookiesFile:        *cookiesFile,
+		Credentials:        credentials,
+		Proxy:              *proxy,
+		CaFile:             *caFile,
+		CertFile:           *certFile,
+		KeyFile:            *keyFile,
+		InsecureSkipVerify: *insecure,
+	})
 	if err != nil {
-		log.Fatal("Can't parse root")
-		panic(1)
+		log.Fatal("Can't configure the http client ", err)
+	}
+
+	if *loginUrl != "" {
+		address, err := url.Parse(*loginUrl)
+		if err != nil {
+			log.Fatal("Can't parse login address ", *loginUrl)
+		}
+		fields := make(url.Values)
+		for _, field := range loginFields {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				log.Fatal("Login field ", field, " is not in the name=value form")
+			}
+			fields.Add(parts[0], parts[1])
+		}
+		if err := defaultClient.Login(*address, fields); err != nil {
+			log.Fatal("Can't log in ", err)
+		}
+	}
+	var client HttpClient = defaultClient
+
+	if *cacheDir != "" {
+		client, err = NewCachingHttpClient(client, *cacheDir, *cacheTtl, *offline)
+		if err != nil {
+			log.Fatal("Can't open cache ", *cacheDir, " ", err)
+		}
+	} else if *offline {
+		log.Fatal("-offline requires -cache")
+	}
+	if *offline && *detectSoft404s {
+		log.Fatal("-soft-404 probes the hosts, it can't be used with -offline")
+	}
+
+	if len(seedSitemaps) > 0 {
+		locations := make([]url.URL, 0, len(seedSitemaps))
+		for _, raw := range seedSitemaps {
+			location, err := url.Parse(raw)
+			if err != nil {
+				log.Fatal("Can't parse sitemap address ", raw)
+			}
+			locations = append(locations, *location)
+		}
+		listed, err := FetchSitemapUrls(client, locations)
+		if err != nil {
+			log.Error("Can't seed from the sitemaps ", err)
+		}
+		seeds = append(seeds, listed...)
+	}
+
+	if len(seeds) == 0 {
+		log.Fatal("Usage: sitemapper [options] URL [URL...]")
+	}
+	root := seeds[0]
+	options.Seeds = seeds[1:]
+
+	fetcherOptions := FetcherOptions{Timings: true}
+	if *previousPath != "" {
+		previous, err := LoadCrawlResult(*previousPath)
+		if err != nil {
+			log.Fatal("Can't load previous crawl ", *previousPath, " ", err)
+		}
+		fetcherOptions.Previous, err = previous.Store()
+		if err != nil {
+			log.Fatal("Can't read previous crawl ", *previousPath, " ", err)
+		}
+	}
+
+	extractorOptions := ExtractorOptions{}
+	if *metricsAddress != "" || *showProgress {
+		metrics := NewMetrics()
+		fetcherOptions.Metrics = metrics
+		extractorOptions.Metrics = metrics
+		options.Metrics = metrics
+	}
+	if *metricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", options.Metrics)
+		go func() {
+			log.Fatal("Can't serve metrics ", http.ListenAndServe(*metricsAddress, mux))
+		}()
 	}
 
 	// we'll push the addresses of the pages we want to map on this channel
 	addressChan := make(chan url.URL)
 
-	client := &DefaultHttpClient{}
-
 	// the http fetchers will read the addresses, fetch the pages and push them down the pagesChan
-	pagesChan := StartHttpFetchers(addressChan, client)
+	pagesChan := StartHttpFetchersWithOptions(addressChan, client, fetcherOptions)
 	// the link extractor will read the pages, parse and extract the contained links and push them down the linksChan
-	linksChan := StartLinkExtractor(pagesChan)
+	linksChan := StartLinkExtractorWithOptions(pagesChan, extractorOptions)
+
 	// the MapSite will act both as the first and the last link in the chain of channels
 	// will push the root down the addressChan, wait other links on the links chan and
 	// will send those on the addressChan, wash rinse repeat
-	siteMap := MapSite(*root, addressChan, linksChan)
+	var progress *Progress
+	if *showProgress {
+		interval := *progressInterval
+		if progressInPlace {
+			interval = 500 * time.Millisecond
+		}
+		progress = StartProgress(os.Stderr, options.Metrics, progressInPlace, interval)
+	}
+
+	pages, err := MapSiteWithOptions(root, addressChan, linksChan, options)
+	if progress != nil {
+		progress.Stop()
+	}
+	if err != nil {
+		log.Error("Crawling interrupted, printing partial results ", err)
+	}
 
-	siteMap.Print(*root)
+	if *detectSoft404s {
+		// the probes bypass the cache, a made up address is never worth keeping
+		flagged, err := DetectSoft404s(defaultClient, pages, DefaultNearDuplicateDistance)
+		if err != nil {
+			log.Error("Can't detect soft 404s ", err)
+		} else if len(flagged) > 0 {
+			log.Warn(len(flagged), " pages look like soft 404s")
+		}
+	}
 
-}
+	if *savePath != "" {
+		if err := SaveCrawlResult(*savePath, root, pages); err != nil {
+			log.Error("Can't save crawl result ", *savePath, " ", err)
+		}
+	}
 
+	siteMap, err := ToPagesMap(pages)
+	if err != nil {
+		log.Fatal("Can't read crawl results ", err)
+	}
+
+	siteMap.PrintFrom(seeds)
+
+	if options.Parameters != nil {
+		if stripped := options.Parameters.Report(); !stripped.IsEmpty() {
+			fmt.Println()
+			stripped.WriteText(os.Stdout)
+		}
+	}
+
+	if options.Traps != nil {
+		if traps := options.Traps.Report(); !traps.IsEmpty() {
+			fmt.Println()
+			traps.WriteText(os.Stdout)
+		}
+	}
+
+	if *xmlPath != "" {
+		file, err := os.Create(*xmlPath)
+		if err == nil {
+			err = WriteSitemapXml(file, pages, root, PageRank(siteMap, DefaultPageRankOptions()))
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			log.Error("Can't write sitemap ", *xmlPath, " ", err)
+		}
+	}
+
+	if *compareSitemap {
+		locations := make([]url.URL, 0, len(sitemaps))
+		for _, raw := range sitemaps {
+			location, err := url.Parse(raw)
+			if err != nil {
+				log.Fatal("Can't parse sitemap address ", raw)
+			}
+			locations = append(locations, *root.ResolveReference(location))
+		}
+		if len(locations) == 0 {
+			locations = DiscoverSitemaps(client, root)
+		}
+
+		listed, err := FetchSitemapUrls(client, locations)
+		if err != nil {
+			// every crawled page would look missing from the sitemap
+			log.Error("Can't compare with sitemap ", err)
+		} else {
+			comparison, err := CompareWithSitemap(pages, listed, root)
+			if err != nil {
+				log.Fatal("Can't compare with sitemap ", err)
+			}
+			fmt.Println()
+			comparison.WriteText(os.Stdout)
+		}
+	}
+
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+type xmlUrlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Urls    []xmlUrl `xml:"url"`
+}
+
+type xmlUrl struct {
+	Loc      string `xml:"loc"`
+	LastMod  string `xml:"lastmod,omitempty"`
+	Priority string `xml:"priority,omitempty"`
+}
+
+// Maps the scores on the 0.1 - 1.0 range of the sitemap <priority>, the best
+// scoring page gets 1.0
+func sitemapPriorities(scores map[url.URL]float64) map[url.URL]float64 {
+	best := 0.0
+	for _, score := range scores {
+		best = math.Max(best, score)
+	}
+	priorities := make(map[url.URL]float64, len(scores))
+	for address, score := range scores {
+		priority := 1.0
+		if best > 0 {
+			priority = 0.1 + 0.9*score/best
+		}
+		priorities[address] = math.Round(priority*10) / 10
+	}
+	return priorities
+}
+
+// True for the pages search engines should index: reached following links on
+// the host of the root and fetched successfully, neither redirecting, soft
+// 404s, noindex nor canonicalised to another address
+func belongsInSitemap(page HtmlPageLinks, root url.URL) bool {
+	return isSameHost(&root, &page.Address) &&
+		!page.Response.StatusOnly &&
+		!isBrokenStatus(page.Response.StatusCode) &&
+		page.Response.RedirectedTo == "" &&
+		page.Info.Soft404 == "" &&
+		!isNoIndex(page.Info) &&
+		(page.Info.Canonical == "" || page.Info.Canonical == page.Address.String())
+}
+
+// Writes a sitemap.xml listing the pages of the root's host worth indexing (see
+// belongsInSitemap), the <priority> of each page comes from its score (e.g.
+// PageRank), pages without a score get no priority. <lastmod> comes from the
+// Last-Modified header
+func WriteSitemapXml(w io.Writer, store PageStore, root url.URL, scores map[url.URL]float64) error {
+	priorities := sitemapPriorities(scores)
+
+	urlSet := xmlUrlSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", Urls: make([]xmlUrl, 0, store.Len())}
+	err := store.Each(func(page HtmlPageLinks) error {
+		if !belongsInSitemap(page, root) {
+			return nil
+		}
+		entry := xmlUrl{Loc: page.Address.String()}
+		if lastModified, err := http.ParseTime(page.Response.LastModified); err == nil {
+			entry.LastMod = lastModified.UTC().Format("2006-01-02T15:04:05Z07:00")
+		}
+		if priority, found := priorities[page.Address]; found {
+			entry.Priority = fmt.Sprintf("%.1f", priority)
+		}
+		urlSet.Urls = append(urlSet.Urls, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(urlSet.Urls, func(i, j int) bool {
+		return urlSet.Urls[i].Loc < urlSet.Urls[j].Loc
+	})
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(urlSet); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+
+	"github.com/PuerkitoBio/goquery"
+	log "github.com/sirupsen/logrus"
+)
+
+// Titles of error pages served with a 200
+var soft404Title = regexp.MustCompile(`(?i)(\b404\b|not found|page (does not|doesn't|no longer) exists?|no longer available)`)
+
+// How a host answers for a page that does not exist
+type HostFingerprint struct {
+	StatusCode int
+	// where the host sends the visitors of missing pages, e.g. the home page
+	RedirectedTo string
+	Info         PageInfo
+}
+
+// true if the host says the page does not exist the way it should
+func (fingerprint HostFingerprint) isHardNotFound() bool {
+	return isBrokenStatus(fingerprint.StatusCode)
+}
+
+// An address of the host that can't exist
+func probeAddress(root url.URL) (url.URL, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return url.URL{}, err
+	}
+	return url.URL{Scheme: root.Scheme, Host: root.Host, Path: "/" + hex.EncodeToString(random) + "-not-found"}, nil
+}
+
+// Requests a random address of the host of root to learn how it answers for
+// missing pages
+func ProbeHost(client HttpClient, root url.URL) (HostFingerprint, error) {
+	address, err := probeAddress(root)
+	if err != nil {
+		return HostFingerprint{}, err
+	}
+	resp, err := client.Get(address)
+	if err != nil {
+		return HostFingerprint{}, err
+	}
+	defer resp.Body.Close()
+
+	fingerprint := HostFingerprint{StatusCode: resp.StatusCode}
+	if resp.Request != nil && resp.Request.URL != nil && resp.Request.URL.String() != address.String() {
+		fingerprint.RedirectedTo = resp.Request.URL.String()
+	}
+	if fingerprint.isHardNotFound() {
+		return fingerprint, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return HostFingerprint{}, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return HostFingerprint{}, err
+	}
+	fingerprint.Info = extractPageInfo(doc, address)
+	return fingerprint, nil
+}
+
+// Why the page looks like a soft 404 (an error page answering 200), empty if it does not
+func soft404Reason(page HtmlPageLinks, fingerprint *HostFingerprint, maxDistance int) string {
+	if isBrokenStatus(page.Response.StatusCode) {
+		return ""
+	}
+
+	if fingerprint != nil && !fingerprint.isHardNotFound() {
+		if fingerprint.RedirectedTo != "" && page.Response.RedirectedTo == fingerprint.RedirectedTo {
+			return "redirects to " + fingerprint.RedirectedTo + " like missing pages do"
+		}
+		if fingerprint.RedirectedTo == "" && fingerprint.Info.WordCount > 0 && page.Info.WordCount > 0 &&
+			HammingDistance(fingerprint.Info.SimHash, page.Info.SimHash) <= maxDistance {
+			return "content similar to the error page of the host"
+		}
+	}
+
+	if soft404Title.MatchString(page.Info.Title) {
+		return fmt.Sprintf("title %q", page.Info.Title)
+	}
+	return ""
+}
+
+// Probes every host of the crawl and flags the pages that look like soft 404s
+// setting their Info.Soft404, returns the flagged pages. Hosts that can't be
+// probed are only checked against the usual titles of error pages
+func DetectSoft404s(client HttpClient, store PageStore, maxDistance int) ([]url.URL, error) {
+	hosts := make(map[string]url.URL)
+	err := store.Each(func(page HtmlPageLinks) error {
+		if !page.Response.StatusOnly {
+			hosts[page.Address.Scheme+"://"+page.Address.Host] = page.Address
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints := make(map[string]*HostFingerprint, len(hosts))
+	for host, address := range hosts {
+		fingerprint, err := ProbeHost(client, address)
+		if err != nil {
+			log.Warn("Can't probe ", host, " for soft 404s ", err)
+			continue
+		}
+		log.Debug("Missing pages of ", host, " answer ", fingerprint.StatusCode)
+		fingerprints[host] = &fingerprint
+	}
+
+	// stores can't be updated while iterating over them
+	flagged := make([]url.URL, 0)
+	changed := make([]HtmlPageLinks, 0)
+	err = store.Each(func(page HtmlPageLinks) error {
+		// only requested for their status, their content was not parsed
+		if page.Response.StatusOnly {
+			return nil
+		}
+		fingerprint := fingerprints[page.Address.Scheme+"://"+page.Address.Host]
+		reason := soft404Reason(page, fingerprint, maxDistance)
+		if reason != "" {
+			flagged = append(flagged, page.Address)
+		}
+		if reason != page.Info.Soft404 {
+			page.Info.Soft404 = reason
+			changed = append(changed, page)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, page := range changed {
+		if err := store.Put(page); err != nil {
+			return nil, err
+		}
+	}
+	return flagged, nil
+}
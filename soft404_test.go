@@ -0,0 +1,140 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// Answers every address the same way, as a host would for missing pages
+type MissingPageHttpClientMock struct {
+	status int
+	body string
+	redirect *url.URL
+	requested []url.URL
+}
+
+func (client *MissingPageHttpClientMock) Get (address url.URL) (resp *http.Response, err error) {
+	client.requested = append(client.requested, address)
+	recorder := httptest.NewRecorder()
+	recorder.WriteHeader(client.status)
+	recorder.Body = bytes.NewBufferString(client.body)
+	resp = recorder.Result()
+	if client.redirect != nil {
+		resp.Request = &http.Request{URL: client.redirect}
+	}
+	return resp, nil
+}
+
+var _ = Describe("DetectSoft404s", func() {
+
+	var (
+		pageUrl *url.URL
+		missingUrl *url.URL
+		titledUrl *url.URL
+		store MemoryPageStore
+	)
+
+	errorText := "Sorry, the page you were looking for has been moved or never existed, go back to the home page"
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		missingUrl, _ = url.Parse("https://www.google.com/old-product")
+		titledUrl, _ = url.Parse("https://www.google.com/gone")
+
+		ok := ResponseInfo{StatusCode: 200}
+		words := strings.Fields(errorText)
+
+		store = make(MemoryPageStore)
+		store.Put(HtmlPageLinks{
+			Address: *pageUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "Google", WordCount: 3, SimHash: SimHash([]string{"search", "the", "web"})},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *missingUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "Oops", WordCount: len(words), SimHash: SimHash(words)},
+		})
+		store.Put(HtmlPageLinks{
+			Address: *titledUrl,
+			LinksTo: []url.URL{},
+			Response: ok,
+			Info: PageInfo{Title: "Page Not Found", WordCount: 1, SimHash: SimHash([]string{"gone"})},
+		})
+	})
+
+	It("should flag the pages similar to the error page of the host", func() {
+		client := &MissingPageHttpClientMock{
+			status: 200,
+			body: "<html><head><title>Oops</title></head><body><p>" + errorText + "</p></body></html>",
+		}
+
+		flagged, err := DetectSoft404s(client, store, DefaultNearDuplicateDistance)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(flagged).To(ConsistOf(*missingUrl, *titledUrl))
+		// one probe per host, on an address that can't exist
+		Expect(client.requested).To(HaveLen(1))
+		Expect(client.requested[0].Host).To(Equal(pageUrl.Host))
+		Expect(client.requested[0].Path).To(HaveSuffix("-not-found"))
+
+		missing, _, _ := store.Get(*missingUrl)
+		Expect(missing.Info.Soft404).To(Equal("content similar to the error page of the host"))
+		titled, _, _ := store.Get(*titledUrl)
+		Expect(titled.Info.Soft404).To(Equal(`title "Page Not Found"`))
+		page, _, _ := store.Get(*pageUrl)
+		Expect(page.Info.Soft404).To(BeEmpty())
+	})
+
+	It("should only look at titles when the host answers 404", func() {
+		client := &MissingPageHttpClientMock{status: 404, body: errorText}
+
+		flagged, err := DetectSoft404s(client, store, DefaultNearDuplicateDistance)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(flagged).To(ConsistOf(*titledUrl))
+	})
+
+	It("should ignore the pages requested for their status only", func() {
+		otherUrl, _ := url.Parse("https://www.bing.com/not-found")
+		store.Put(HtmlPageLinks{
+			Address: *otherUrl,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 200, StatusOnly: true},
+			Info: PageInfo{Title: "Page Not Found"},
+		})
+		client := &MissingPageHttpClientMock{status: 404, body: errorText}
+
+		flagged, err := DetectSoft404s(client, store, DefaultNearDuplicateDistance)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(flagged).To(ConsistOf(*titledUrl))
+		// its host is not probed
+		Expect(client.requested).To(HaveLen(1))
+	})
+
+	It("should flag the pages redirecting where missing pages do", func() {
+		redirectedUrl, _ := url.Parse("https://www.google.com/discontinued")
+		store.Put(HtmlPageLinks{
+			Address: *redirectedUrl,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 200, RedirectedTo: pageUrl.String()},
+			Info: PageInfo{Title: "Google", WordCount: 3},
+		})
+		client := &MissingPageHttpClientMock{status: 200, body: "<title>Google</title>", redirect: pageUrl}
+
+		flagged, err := DetectSoft404s(client, store, DefaultNearDuplicateDistance)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(flagged).To(ConsistOf(*redirectedUrl, *titledUrl))
+	})
+
+})
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Strongly connected components of the internal link graph: within a component
+// every page can reach every other one following links. Computed with Tarjan's
+// algorithm, iterating rather than recursing (as Print does) so that long link
+// chains can't overflow the stack
+func StronglyConnectedComponents(pages PagesMap) [][]url.URL {
+	links := internalLinks(pages)
+
+	index := make(map[url.URL]int, len(pages))
+	lowlink := make(map[url.URL]int, len(pages))
+	onStack := make(map[url.URL]bool)
+	stack := make([]url.URL, 0)
+	components := make([][]url.URL, 0)
+	next := 0
+
+	// the simulated call stack: the page being visited and the successors still to visit
+	type frame struct {
+		address    url.URL
+		successors []url.URL
+	}
+
+	successorsOf := func(address url.URL) []url.URL {
+		successors := make([]url.URL, 0, len(links[address]))
+		for target := range links[address] {
+			successors = append(successors, target)
+		}
+		return successors
+	}
+
+	visit := func(address url.URL) frame {
+		index[address] = next
+		lowlink[address] = next
+		next++
+		stack = append(stack, address)
+		onStack[address] = true
+		return frame{address, successorsOf(address)}
+	}
+
+	for start := range pages {
+		if _, visited := index[start]; visited {
+			continue
+		}
+
+		calls := []frame{visit(start)}
+		for len(calls) > 0 {
+			top := &calls[len(calls)-1]
+
+			if len(top.successors) > 0 {
+				successor := top.successors[0]
+				top.successors = top.successors[1:]
+				if _, visited := index[successor]; !visited {
+					calls = append(calls, visit(successor))
+				} else if onStack[successor] && index[successor] < lowlink[top.address] {
+					lowlink[top.address] = index[successor]
+				}
+				continue
+			}
+
+			// all successors visited, "return" to the caller
+			address := top.address
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				caller := calls[len(calls)-1].address
+				if lowlink[address] < lowlink[caller] {
+					lowlink[caller] = lowlink[address]
+				}
+			}
+
+			if lowlink[address] == index[address] {
+				component := make([]url.URL, 0)
+				for {
+					last := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[last] = false
+					component = append(component, last)
+					if last == address {
+						break
+					}
+				}
+				components = append(components, component)
+			}
+		}
+	}
+
+	return components
+}
+
+// Pages from which no chain of links leads back to the root, broken pages
+// (which have no links) are left out
+func CannotReachRoot(pages PagesMap, broken map[url.URL]bool, root url.URL) []url.URL {
+	// visit the reversed graph starting from the root
+	reversed := make(map[url.URL][]url.URL, len(pages))
+	for address, targets := range internalLinks(pages) {
+		for target := range targets {
+			reversed[target] = append(reversed[target], address)
+		}
+	}
+
+	reaches := map[url.URL]bool{root: true}
+	queue := []url.URL{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, source := range reversed[current] {
+			if !reaches[source] {
+				reaches[source] = true
+				queue = append(queue, source)
+			}
+		}
+	}
+
+	stranded := make([]url.URL, 0)
+	for address := range pages {
+		if !reaches[address] && !broken[address] {
+			stranded = append(stranded, address)
+		}
+	}
+	return stranded
+}
+
+// The section of a page: the first depth segments of its path (e.g. /blog/2017
+// with depth 2), "/" for pages above that
+func SectionOf(address url.URL, depth int) string {
+	segments := make([]string, 0, depth)
+	for _, segment := range strings.Split(address.Path, "/") {
+		if segment == "" {
+			continue
+		}
+		if len(segments) == depth {
+			break
+		}
+		segments = append(segments, segment)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// Link structure of the site
+type StructureReport struct {
+	// strongly connected components with more than one page, biggest first
+	Components [][]string `json:"components"`
+	// pages that can't link their way back to the root
+	CannotReachRoot []string `json:"cannot_reach_root"`
+	// Matrix[i][j] counts the internal links from pages in Sections[i] to pages in Sections[j]
+	Sections []string `json:"sections"`
+	Matrix   [][]int  `json:"matrix"`
+}
+
+func toStrings(addresses []url.URL) []string {
+	res := make([]string, len(addresses))
+	for i, address := range addresses {
+		res[i] = address.String()
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Analyses the link structure, sections are grouped by the first sectionDepth path segments
+func AnalyzeStructure(pages PagesMap, broken map[url.URL]bool, root url.URL, sectionDepth int) StructureReport {
+	report := StructureReport{
+		Components:      make([][]string, 0),
+		CannotReachRoot: toStrings(CannotReachRoot(pages, broken, root)),
+		Sections:        make([]string, 0),
+	}
+
+	for _, component := range StronglyConnectedComponents(pages) {
+		if len(component) > 1 {
+			report.Components = append(report.Components, toStrings(component))
+		}
+	}
+	sort.Slice(report.Components, func(i, j int) bool {
+		if len(report.Components[i]) != len(report.Components[j]) {
+			return len(report.Components[i]) > len(report.Components[j])
+		}
+		return report.Components[i][0] < report.Components[j][0]
+	})
+
+	sectionIndex := make(map[string]int)
+	for address := range pages {
+		sectionIndex[SectionOf(address, sectionDepth)] = 0
+	}
+	for section := range sectionIndex {
+		report.Sections = append(report.Sections, section)
+	}
+	sort.Strings(report.Sections)
+	report.Matrix = make([][]int, len(report.Sections))
+	for i, section := range report.Sections {
+		sectionIndex[section] = i
+		report.Matrix[i] = make([]int, len(report.Sections))
+	}
+
+	for address, targets := range internalLinks(pages) {
+		from := sectionIndex[SectionOf(address, sectionDepth)]
+		for target := range targets {
+			report.Matrix[from][sectionIndex[SectionOf(target, sectionDepth)]]++
+		}
+	}
+
+	return report
+}
+
+func (report StructureReport) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (report StructureReport) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Strongly connected components (%d)\n", len(report.Components))
+	for i, component := range report.Components {
+		fmt.Fprintf(w, "  #%d, %d pages\n", i+1, len(component))
+		for _, address := range component {
+			fmt.Fprintln(w, "    ", address)
+		}
+	}
+
+	fmt.Fprintf(w, "Pages that can't reach the root (%d)\n", len(report.CannotReachRoot))
+	for _, address := range report.CannotReachRoot {
+		fmt.Fprintln(w, "  ", address)
+	}
+
+	fmt.Fprintln(w, "Links between sections (rows link to columns)")
+	for i, section := range report.Sections {
+		fmt.Fprintf(w, "  %-3d %s\n", i, section)
+	}
+	fmt.Fprint(w, "     ")
+	for i := range report.Sections {
+		fmt.Fprintf(w, " %5d", i)
+	}
+	fmt.Fprintln(w)
+	for i, row := range report.Matrix {
+		fmt.Fprintf(w, "  %-3d", i)
+		for _, count := range row {
+			fmt.Fprintf(w, " %5d", count)
+		}
+		fmt.Fprintln(w)
+	}
+}
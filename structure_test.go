@@ -0,0 +1,68 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"net/url"
+)
+
+var _ = Describe("AnalyzeStructure", func() {
+
+	var (
+		pageUrl *url.URL
+		blogUrl *url.URL
+		postUrl *url.URL
+		otherPostUrl *url.URL
+		archiveUrl *url.URL
+		pages PagesMap
+	)
+
+	BeforeEach(func() {
+		pageUrl, _ = url.Parse("https://www.google.com/")
+		blogUrl, _ = url.Parse("https://www.google.com/blog")
+		postUrl, _ = url.Parse("https://www.google.com/blog/post")
+		otherPostUrl, _ = url.Parse("https://www.google.com/blog/other-post")
+		archiveUrl, _ = url.Parse("https://www.google.com/archive/2017")
+
+		pages = PagesMap{
+			*pageUrl: {*blogUrl},
+			*blogUrl: {*pageUrl, *postUrl},
+			*postUrl: {*otherPostUrl},
+			*otherPostUrl: {*postUrl, *archiveUrl},
+			*archiveUrl: {},
+		}
+	})
+
+	It("should find the strongly connected components", func() {
+		components := StronglyConnectedComponents(pages)
+
+		Expect(components).To(ConsistOf(
+			ConsistOf(*pageUrl, *blogUrl),
+			ConsistOf(*postUrl, *otherPostUrl),
+			ConsistOf(*archiveUrl),
+		))
+	})
+
+	It("should find the pages that can't reach the root", func() {
+		Expect(CannotReachRoot(pages, nil, *pageUrl)).To(ConsistOf(*postUrl, *otherPostUrl, *archiveUrl))
+	})
+
+	It("should leave broken pages out of the ones that can't reach the root", func() {
+		broken := map[url.URL]bool{*archiveUrl: true}
+		Expect(CannotReachRoot(pages, broken, *pageUrl)).To(ConsistOf(*postUrl, *otherPostUrl))
+	})
+
+	It("should count the links between sections", func() {
+		report := AnalyzeStructure(pages, nil, *pageUrl, 1)
+
+		Expect(report.Components).To(HaveLen(2))
+		Expect(report.Sections).To(Equal([]string{"/", "/archive", "/blog"}))
+		Expect(report.Matrix).To(Equal([][]int{
+			{0, 0, 1},
+			{0, 0, 0},
+			{1, 1, 3},
+		}))
+	})
+
+})
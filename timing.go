@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Where the time went while fetching a page. DNS, Connect and TLS are zero
+// when a kept alive connection was reused and add up over redirects, they
+// are only known when the client supports tracing (ContextHttpClient)
+type RequestTiming struct {
+	DNS     time.Duration `json:"dns_ns,omitempty"`
+	Connect time.Duration `json:"connect_ns,omitempty"`
+	TLS     time.Duration `json:"tls_ns,omitempty"`
+	// from the start of the request to the first byte of the (last) response
+	FirstByte time.Duration `json:"first_byte_ns,omitempty"`
+	// until the whole body was read
+	Total time.Duration `json:"total_ns"`
+	// size of the body
+	Bytes int64 `json:"bytes"`
+}
+
+// Collects the httptrace events of a request, callbacks may come from
+// different goroutines
+type requestTracer struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart map[string]time.Time
+	tlsStart     time.Time
+	timing       RequestTiming
+}
+
+func newRequestTracer() *requestTracer {
+	return &requestTracer{start: time.Now(), connectStart: make(map[string]time.Time)}
+}
+
+func (tracer *requestTracer) context(parent context.Context) context.Context {
+	locked := func(fn func()) {
+		tracer.mutex.Lock()
+		defer tracer.mutex.Unlock()
+		fn()
+	}
+	return httptrace.WithClientTrace(parent, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			locked(func() { tracer.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			locked(func() { tracer.timing.DNS += time.Since(tracer.dnsStart) })
+		},
+		ConnectStart: func(network, address string) {
+			locked(func() { tracer.connectStart[network+address] = time.Now() })
+		},
+		ConnectDone: func(network, address string, err error) {
+			locked(func() {
+				// dialing several addresses at once, only the one in use counts
+				if err == nil {
+					tracer.timing.Connect += time.Since(tracer.connectStart[network+address])
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			locked(func() { tracer.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			locked(func() { tracer.timing.TLS += time.Since(tracer.tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			locked(func() { tracer.timing.FirstByte = time.Since(tracer.start) })
+		},
+	})
+}
+
+// The timing of the request, once its body of the given size was read
+func (tracer *requestTracer) finish(bytes int) *RequestTiming {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	timing := tracer.timing
+	timing.Total = time.Since(tracer.start)
+	timing.Bytes = int64(bytes)
+	return &timing
+}
+
+// A page and how long it took
+type PageTiming struct {
+	Address string `json:"address"`
+	RequestTiming
+}
+
+// Distribution of one of the metrics over the fetched pages
+type Percentiles struct {
+	Metric string  `json:"metric"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	Max    float64 `json:"max"`
+}
+
+type TimingReport struct {
+	// pages with a timing
+	Pages int `json:"pages"`
+	// durations in milliseconds, sizes in bytes
+	Summary []Percentiles `json:"summary"`
+	// the pages taking longest to fetch, slowest first
+	Slowest []PageTiming `json:"slowest"`
+}
+
+// value at the given percentile (nearest rank) of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func percentilesOf(metric string, values []float64) Percentiles {
+	sort.Float64s(values)
+	summary := Percentiles{Metric: metric}
+	if len(values) > 0 {
+		summary.P50 = percentile(values, 50)
+		summary.P90 = percentile(values, 90)
+		summary.P95 = percentile(values, 95)
+		summary.P99 = percentile(values, 99)
+		summary.Max = values[len(values)-1]
+	}
+	return summary
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// Summarises the timings of the pages, listing the slowest ones. Pages fetched
+// without timing (e.g. crawls saved before timings were recorded) and the ones
+// requested for their status only are ignored
+func AnalyzeTimings(store PageStore, slowest int) (TimingReport, error) {
+	timings := make([]PageTiming, 0, store.Len())
+	err := store.Each(func(page HtmlPageLinks) error {
+		if page.Response.Timing != nil && !page.Response.StatusOnly {
+			timings = append(timings, PageTiming{page.Address.String(), *page.Response.Timing})
+		}
+		return nil
+	})
+	if err != nil {
+		return TimingReport{}, err
+	}
+
+	metrics := []struct {
+		name  string
+		value func(RequestTiming) float64
+	}{
+		{"dns", func(timing RequestTiming) float64 { return milliseconds(timing.DNS) }},
+		{"connect", func(timing RequestTiming) float64 { return milliseconds(timing.Connect) }},
+		{"tls", func(timing RequestTiming) float64 { return milliseconds(timing.TLS) }},
+		{"first byte", func(timing RequestTiming) float64 { return milliseconds(timing.FirstByte) }},
+		{"total", func(timing RequestTiming) float64 { return milliseconds(timing.Total) }},
+		{"bytes", func(timing RequestTiming) float64 { return float64(timing.Bytes) }},
+	}
+
+	report := TimingReport{Pages: len(timings), Summary: make([]Percentiles, 0, len(metrics))}
+	for _, metric := range metrics {
+		values := make([]float64, len(timings))
+		for i, timing := range timings {
+			values[i] = metric.value(timing.RequestTiming)
+		}
+		report.Summary = append(report.Summary, percentilesOf(metric.name, values))
+	}
+
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Total != timings[j].Total {
+			return timings[i].Total > timings[j].Total
+		}
+		return timings[i].Address < timings[j].Address
+	})
+	if len(timings) > slowest {
+		timings = timings[:slowest]
+	}
+	report.Slowest = timings
+
+	return report, nil
+}
+
+func (report TimingReport) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (report TimingReport) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Timings of %d pages (milliseconds, bytes)\n", report.Pages)
+	fmt.Fprintf(w, "  %-12s %10s %10s %10s %10s %10s\n", "", "p50", "p90", "p95", "p99", "max")
+	for _, summary := range report.Summary {
+		fmt.Fprintf(w, "  %-12s %10.1f %10.1f %10.1f %10.1f %10.1f\n",
+			summary.Metric, summary.P50, summary.P90, summary.P95, summary.P99, summary.Max)
+	}
+
+	fmt.Fprintf(w, "Slowest pages (%d)\n", len(report.Slowest))
+	for _, page := range report.Slowest {
+		fmt.Fprintf(w, "  %8.1f ms %10d bytes  %s (first byte %.1f ms)\n",
+			milliseconds(page.Total), page.Bytes, page.Address, milliseconds(page.FirstByte))
+	}
+}
@@ -0,0 +1,75 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+var _ = Describe("AnalyzeTimings", func() {
+
+	var (
+		store MemoryPageStore
+	)
+
+	BeforeEach(func() {
+		store = make(MemoryPageStore)
+		// pages taking 1 to 100 ms
+		for i := 1; i <= 100; i++ {
+			address, _ := url.Parse(fmt.Sprintf("https://www.google.com/%d", i))
+			store.Put(HtmlPageLinks{
+				Address: *address,
+				LinksTo: []url.URL{},
+				Response: ResponseInfo{StatusCode: 200, Timing: &RequestTiming{
+					FirstByte: time.Duration(i) * time.Millisecond / 2,
+					Total: time.Duration(i) * time.Millisecond,
+					Bytes: int64(i * 1000),
+				}},
+			})
+		}
+		notTimed, _ := url.Parse("https://www.google.com/cached")
+		store.Put(HtmlPageLinks{Address: *notTimed, LinksTo: []url.URL{}, Response: ResponseInfo{StatusCode: 200}})
+	})
+
+	It("should summarise the timings with percentiles", func() {
+		report, err := AnalyzeTimings(store, 3)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Pages).To(Equal(100))
+		Expect(report.Summary).To(ContainElement(Percentiles{Metric: "total", P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}))
+		Expect(report.Summary).To(ContainElement(Percentiles{Metric: "bytes", P50: 50000, P90: 90000, P95: 95000, P99: 99000, Max: 100000}))
+		Expect(report.Summary).To(ContainElement(Percentiles{Metric: "dns"}))
+	})
+
+	It("should ignore the pages requested for their status only", func() {
+		canonical, _ := url.Parse("https://www.google.com/canonical")
+		store.Put(HtmlPageLinks{
+			Address: *canonical,
+			LinksTo: []url.URL{},
+			Response: ResponseInfo{StatusCode: 200, StatusOnly: true, Timing: &RequestTiming{Total: time.Second}},
+		})
+
+		report, _ := AnalyzeTimings(store, 1)
+
+		Expect(report.Pages).To(Equal(100))
+		Expect(report.Slowest[0].Address).To(Equal("https://www.google.com/100"))
+	})
+
+	It("should list the slowest pages", func() {
+		report, _ := AnalyzeTimings(store, 3)
+
+		Expect(report.Slowest).To(HaveLen(3))
+		Expect(report.Slowest[0].Address).To(Equal("https://www.google.com/100"))
+		Expect(report.Slowest[2].Address).To(Equal("https://www.google.com/98"))
+
+		var out bytes.Buffer
+		report.WriteText(&out)
+		Expect(out.String()).To(ContainSubstring("Timings of 100 pages"))
+		Expect(out.String()).To(ContainSubstring("100.0 ms     100000 bytes  https://www.google.com/100 (first byte 50.0 ms)"))
+	})
+
+})
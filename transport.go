@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Proxy schemes supported by http.Transport
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true, "socks5h": true}
+
+func (options HttpClientOptions) customTransport() bool {
+	return options.Proxy != "" || options.CaFile != "" || options.CertFile != "" || options.KeyFile != "" ||
+		options.InsecureSkipVerify
+}
+
+// The transport for the proxy and TLS options, http.DefaultTransport when
+// none is set
+func newTransport(options HttpClientOptions) (http.RoundTripper, error) {
+	if !options.customTransport() {
+		return http.DefaultTransport, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if options.Proxy != "" {
+		proxy, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		if !proxySchemes[proxy.Scheme] {
+			return nil, fmt.Errorf("unsupported proxy %q, expected http, https or socks5", options.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	config := &tls.Config{}
+
+	if options.CaFile != "" {
+		pem, err := ioutil.ReadFile(options.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		// the bundle is trusted in addition to the system certificates
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", options.CaFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if options.CertFile != "" || options.KeyFile != "" {
+		if options.CertFile == "" || options.KeyFile == "" {
+			return nil, fmt.Errorf("client certificates need both the certificate and the key")
+		}
+		certificate, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if options.InsecureSkipVerify {
+		log.Warn("TLS certificates are not verified, connections can be intercepted")
+		config.InsecureSkipVerify = true
+	}
+
+	transport.TLSClientConfig = config
+	return transport, nil
+}
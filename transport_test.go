@@ -0,0 +1,146 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Self signed certificate allowed to authenticate clients, returns the paths of
+// the PEM certificate and key
+func writeClientCertificate(dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{CommonName: "sitemapper"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter: time.Now().Add(time.Hour),
+		KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	certificate, _ := x509.ParseCertificate(der)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	Expect(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+	Expect(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)).To(Succeed())
+	return certificate, certFile, keyFile
+}
+
+var _ = Describe("DefaultHttpClient transport", func() {
+
+	var (
+		dir string
+		server *httptest.Server
+		serverUrl *url.URL
+		caFile string
+	)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "sitemapper")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			server.Close()
+			server = nil
+		}
+		os.RemoveAll(dir)
+	})
+
+	startTls := func(server *httptest.Server) *url.URL {
+		caFile = filepath.Join(dir, "ca.pem")
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		Expect(ioutil.WriteFile(caFile, ca, 0600)).To(Succeed())
+		address, _ := url.Parse(server.URL)
+		return address
+	}
+
+	get := func(options HttpClientOptions, address url.URL) error {
+		client, err := NewDefaultHttpClient(options)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := client.Get(address)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	It("should trust the certificate authorities of the bundle", func() {
+		server = httptest.NewTLSServer(ok)
+		serverUrl = startTls(server)
+
+		Expect(get(HttpClientOptions{}, *serverUrl)).NotTo(Succeed())
+		Expect(get(HttpClientOptions{CaFile: caFile}, *serverUrl)).To(Succeed())
+	})
+
+	It("should skip the verification only when asked to", func() {
+		server = httptest.NewTLSServer(ok)
+		serverUrl = startTls(server)
+
+		Expect(get(HttpClientOptions{InsecureSkipVerify: true}, *serverUrl)).To(Succeed())
+	})
+
+	It("should present the client certificate", func() {
+		certificate, certFile, keyFile := writeClientCertificate(dir)
+		clients := x509.NewCertPool()
+		clients.AddCert(certificate)
+
+		server = httptest.NewUnstartedServer(ok)
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+		server.StartTLS()
+		serverUrl = startTls(server)
+
+		Expect(get(HttpClientOptions{CaFile: caFile}, *serverUrl)).NotTo(Succeed())
+		Expect(get(HttpClientOptions{CaFile: caFile, CertFile: certFile, KeyFile: keyFile}, *serverUrl)).To(Succeed())
+	})
+
+	It("should send the requests through the proxy", func() {
+		proxied := make(chan *url.URL, 1)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied <- r.URL
+		}))
+
+		target, _ := url.Parse("http://www.google.invalid/about")
+		Expect(get(HttpClientOptions{Proxy: server.URL}, *target)).To(Succeed())
+		Expect((<-proxied).String()).To(Equal(target.String()))
+	})
+
+	It("should reject invalid configurations", func() {
+		_, err := NewDefaultHttpClient(HttpClientOptions{Proxy: "ftp://proxy:21"})
+		Expect(err).To(HaveOccurred())
+
+		_, err = NewDefaultHttpClient(HttpClientOptions{CertFile: "client.pem"})
+		Expect(err).To(HaveOccurred())
+
+		_, err = NewDefaultHttpClient(HttpClientOptions{Proxy: "socks5://localhost:1080"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+})
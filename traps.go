@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	TrapUrlLength        = "url-length"
+	TrapPathDepth        = "path-depth"
+	TrapRepeatedSegments = "repeated-segments"
+	TrapQueryVariants    = "query-variants"
+)
+
+// Limits past which an address is considered part of a spider trap, 0
+// disables the related check
+type TrapOptions struct {
+	// addresses longer than this
+	MaxUrlLength int
+	// paths with more segments than this
+	MaxPathDepth int
+	// paths where a segment occurs more than this many times (e.g. the
+	// /a/b/a/b/a/b generated by recursive relative links)
+	MaxSegmentRepeats int
+	// distinct query strings followed for the same path (calendars, faceted
+	// search), the ones exceeding the cap are quarantined
+	MaxQueryVariants int
+}
+
+func DefaultTrapOptions() TrapOptions {
+	return TrapOptions{MaxUrlLength: 1024, MaxPathDepth: 15, MaxSegmentRepeats: 3, MaxQueryVariants: 50}
+}
+
+// A family of addresses that was quarantined: none of them is requested
+type TrapPattern struct {
+	// e.g. https://example.com/calendar?*
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason"`
+	// number of quarantined addresses matching the pattern
+	Count int `json:"count"`
+	// the first few of them
+	Examples []string `json:"examples"`
+}
+
+const maxTrapExamples = 3
+
+// Checks the addresses found during the crawl against the TrapOptions and
+// keeps track of the quarantined ones. Addresses must be submitted once (the
+// mapper only checks the ones it has not seen yet)
+type TrapDetector struct {
+	options TrapOptions
+	// distinct query strings accepted so far, by scheme, host and path
+	queries     map[string]map[string]bool
+	quarantined map[string]*TrapPattern
+}
+
+func NewTrapDetector(options TrapOptions) *TrapDetector {
+	return &TrapDetector{
+		options:     options,
+		queries:     make(map[string]map[string]bool),
+		quarantined: make(map[string]*TrapPattern),
+	}
+}
+
+func pathSegments(address url.URL) []string {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(address.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// The address up to the first count path segments, followed by /**
+func prefixPattern(address url.URL, segments []string, count int) string {
+	if count > len(segments) {
+		count = len(segments)
+	}
+	prefix := address.Scheme + "://" + address.Host
+	for _, segment := range segments[:count] {
+		prefix += "/" + segment
+	}
+	return prefix + "/**"
+}
+
+// Returns the pattern and the reason why the address belongs to a trap, if it does
+func (detector *TrapDetector) check(address url.URL) (string, string, bool) {
+	options := detector.options
+	segments := pathSegments(address)
+
+	if options.MaxUrlLength > 0 && len(address.String()) > options.MaxUrlLength {
+		return prefixPattern(address, segments, 1), TrapUrlLength, true
+	}
+
+	if options.MaxPathDepth > 0 && len(segments) > options.MaxPathDepth {
+		return prefixPattern(address, segments, 2), TrapPathDepth, true
+	}
+
+	if options.MaxSegmentRepeats > 0 {
+		occurrences := make(map[string]int)
+		first := make(map[string]int)
+		for i, segment := range segments {
+			if _, found := first[segment]; !found {
+				first[segment] = i
+			}
+			occurrences[segment]++
+			if occurrences[segment] > options.MaxSegmentRepeats {
+				// the loop starts where the segment was first met
+				return prefixPattern(address, segments, first[segment]+1), TrapRepeatedSegments, true
+			}
+		}
+	}
+
+	if options.MaxQueryVariants > 0 && address.RawQuery != "" {
+		path := address.Scheme + "://" + address.Host + address.Path
+		variants, found := detector.queries[path]
+		if !found {
+			variants = make(map[string]bool)
+			detector.queries[path] = variants
+		}
+		if !variants[address.RawQuery] {
+			if len(variants) >= options.MaxQueryVariants {
+				return path + "?*", TrapQueryVariants, true
+			}
+			variants[address.RawQuery] = true
+		}
+	}
+
+	return "", "", false
+}
+
+// True if the address should not be requested, in which case it is recorded
+// in the report
+func (detector *TrapDetector) Quarantine(address url.URL) bool {
+	pattern, reason, trapped := detector.check(address)
+	if !trapped {
+		return false
+	}
+
+	key := reason + " " + pattern
+	quarantined, found := detector.quarantined[key]
+	if !found {
+		quarantined = &TrapPattern{Pattern: pattern, Reason: reason, Examples: make([]string, 0, maxTrapExamples)}
+		detector.quarantined[key] = quarantined
+	}
+	quarantined.Count++
+	if len(quarantined.Examples) < maxTrapExamples {
+		quarantined.Examples = append(quarantined.Examples, address.String())
+	}
+	return true
+}
+
+// The quarantined patterns, the ones matching more addresses first
+func (detector *TrapDetector) Report() TrapReport {
+	patterns := make([]TrapPattern, 0, len(detector.quarantined))
+	for _, pattern := range detector.quarantined {
+		patterns = append(patterns, *pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Count != patterns[j].Count {
+			return patterns[i].Count > patterns[j].Count
+		}
+		return patterns[i].Pattern < patterns[j].Pattern
+	})
+	return TrapReport{patterns}
+}
+
+type TrapReport struct {
+	Patterns []TrapPattern `json:"patterns"`
+}
+
+func (report TrapReport) IsEmpty() bool {
+	return len(report.Patterns) == 0
+}
+
+func (report TrapReport) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (report TrapReport) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Spider traps (%d patterns quarantined)\n", len(report.Patterns))
+	for _, pattern := range report.Patterns {
+		fmt.Fprintf(w, "  [%s] %s, %d addresses\n", pattern.Reason, pattern.Pattern, pattern.Count)
+		for _, example := range pattern.Examples {
+			fmt.Fprintln(w, "    ", example)
+		}
+	}
+}
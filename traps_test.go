@@ -0,0 +1,90 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/mone/sitemapper"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var _ = Describe("TrapDetector", func() {
+
+	var (
+		detector *TrapDetector
+	)
+
+	parse := func(raw string) url.URL {
+		address, _ := url.Parse(raw)
+		return *address
+	}
+
+	BeforeEach(func() {
+		detector = NewTrapDetector(TrapOptions{MaxUrlLength: 100, MaxPathDepth: 5, MaxSegmentRepeats: 2, MaxQueryVariants: 2})
+	})
+
+	It("should let ordinary addresses through", func() {
+		Expect(detector.Quarantine(parse("https://www.google.com/"))).To(BeFalse())
+		Expect(detector.Quarantine(parse("https://www.google.com/about/jobs?team=search"))).To(BeFalse())
+		Expect(detector.Report().IsEmpty()).To(BeTrue())
+	})
+
+	It("should quarantine long and deep addresses", func() {
+		Expect(detector.Quarantine(parse("https://www.google.com/search/" + strings.Repeat("x", 100)))).To(BeTrue())
+		Expect(detector.Quarantine(parse("https://www.google.com/a/b/c/d/e/f"))).To(BeTrue())
+
+		Expect(detector.Report().Patterns).To(ConsistOf(
+			TrapPattern{
+				Pattern: "https://www.google.com/search/**",
+				Reason: TrapUrlLength,
+				Count: 1,
+				Examples: []string{"https://www.google.com/search/" + strings.Repeat("x", 100)},
+			},
+			TrapPattern{
+				Pattern: "https://www.google.com/a/b/**",
+				Reason: TrapPathDepth,
+				Count: 1,
+				Examples: []string{"https://www.google.com/a/b/c/d/e/f"},
+			},
+		))
+	})
+
+	It("should quarantine addresses repeating path segments", func() {
+		Expect(detector.Quarantine(parse("https://www.google.com/docs/a/b/a"))).To(BeFalse())
+		Expect(detector.Quarantine(parse("https://www.google.com/docs/a/b/a/a"))).To(BeTrue())
+
+		Expect(detector.Report().Patterns[0].Pattern).To(Equal("https://www.google.com/docs/a/**"))
+		Expect(detector.Report().Patterns[0].Reason).To(Equal(TrapRepeatedSegments))
+	})
+
+	It("should cap the query variants of a path", func() {
+		for day := 1; day <= 5; day++ {
+			detector.Quarantine(parse(fmt.Sprintf("https://www.google.com/calendar?day=%d", day)))
+		}
+		// variants already accepted are still accepted
+		Expect(detector.Quarantine(parse("https://www.google.com/calendar?day=1"))).To(BeFalse())
+		Expect(detector.Quarantine(parse("https://www.google.com/agenda?day=5"))).To(BeFalse())
+
+		Expect(detector.Report().Patterns).To(Equal([]TrapPattern{{
+			Pattern: "https://www.google.com/calendar?*",
+			Reason: TrapQueryVariants,
+			Count: 3,
+			Examples: []string{
+				"https://www.google.com/calendar?day=3",
+				"https://www.google.com/calendar?day=4",
+				"https://www.google.com/calendar?day=5",
+			},
+		}}))
+	})
+
+	It("should write the report as text", func() {
+		detector.Quarantine(parse("https://www.google.com/a/b/c/d/e/f"))
+
+		var out bytes.Buffer
+		detector.Report().WriteText(&out)
+		Expect(out.String()).To(ContainSubstring("[path-depth] https://www.google.com/a/b/**, 1 addresses"))
+	})
+
+})